@@ -0,0 +1,276 @@
+package api
+
+import (
+	"crypto/subtle"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flash-go/internal/core"
+	"flash-go/internal/metrics"
+	"flash-go/internal/models"
+	"flash-go/internal/redis"
+	"flash-go/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	adminTokenHeader = "X-Admin-Token"
+	defaultQueuePeek = 20
+	maxQueuePeek     = 1000
+)
+
+// DeadLetter describes a job in the dead-letter list.
+type DeadLetter struct {
+	Token      string             `json:"token"`
+	Status     models.CheckStatus `json:"status"`
+	Message    string             `json:"message"`
+	Attempts   int                `json:"attempts"`
+	FinishedAt int64              `json:"finished_at"`
+}
+
+// QueuedJob is a job ID waiting in a queue with its stored status.
+type QueuedJob struct {
+	Token  string              `json:"token"`
+	Status *models.CheckStatus `json:"status"`
+}
+
+// QueueSnapshot describes the head of one queue.
+type QueueSnapshot struct {
+	Queue  string      `json:"queue"`
+	Length int64       `json:"length"`
+	Jobs   []QueuedJob `json:"jobs"`
+}
+
+// requireAdmin rejects requests without the configured admin token. Admin
+// routes are unavailable when no token is configured.
+func (h *Handler) requireAdmin(c *gin.Context) {
+	if h.adminToken == "" {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "admin API disabled"})
+		return
+	}
+	token := c.GetHeader(adminTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+		return
+	}
+	c.Next()
+}
+
+// ListDeadLetters handles GET /admin/dead-letters
+// Lists jobs that exhausted their retries with their last error message.
+// Jobs whose data has expired are listed with an empty message.
+func (h *Handler) ListDeadLetters(c *gin.Context) {
+	ctx := c.Request.Context()
+	ids, err := h.redis.DeadJobIDs(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list dead letters"})
+		return
+	}
+	jobs, err := h.redis.GetJobs(ctx, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch jobs"})
+		return
+	}
+
+	letters := make([]DeadLetter, 0, len(ids))
+	for i, id := range ids {
+		letter := DeadLetter{Token: strconv.FormatUint(id, 10)}
+		if job := jobs[i]; job != nil {
+			letter.Status = models.CheckStatus{ID: job.Status.ID(), Description: job.Status.Description()}
+			letter.Message = job.Output.Message
+			letter.Attempts = job.Attempts
+			letter.FinishedAt = h.timestampUnit.Scale(job.FinishedAt)
+		}
+		letters = append(letters, letter)
+	}
+	c.JSON(http.StatusOK, gin.H{"dead_letters": letters})
+}
+
+// RequeueDeadLetter handles POST /admin/dead-letters/:token/requeue
+// Resets a dead job and enqueues it again on its original queue.
+func (h *Handler) RequeueDeadLetter(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("token"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	job, err := h.redis.GetJob(ctx, jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	removed, err := h.redis.RemoveDeadJob(ctx, jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update dead letters"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job is not in the dead-letter list"})
+		return
+	}
+
+	queue := redis.Queue(job.Queue)
+	if queue == "" {
+		queue = redis.QueueMain
+	}
+	job.Status = models.JobStatus{Kind: models.StatusQueued}
+	job.StartedAt = 0
+	job.FinishedAt = 0
+	job.Attempts = 0
+	// Its in-flight slot was released when it died; the requeue is not counted.
+	job.InFlightKey = ""
+	job.Output = models.JobOutput{}
+	if err := h.redis.Enqueue(ctx, job, queue); err != nil {
+		_ = h.redis.PushDeadJob(ctx, jobID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enqueue job"})
+		return
+	}
+	metrics.JobsEnqueued.WithLabelValues(job.Language.Name).Inc()
+	c.JSON(http.StatusOK, core.NewCheckResponse(job, h.timestampUnit))
+}
+
+// GetRawJob handles GET /admin/submissions/:token/raw
+// Returns the job exactly as stored, including settings and language config,
+// for debugging verdicts.
+func (h *Handler) GetRawJob(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("token"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token format"})
+		return
+	}
+
+	job, err := h.redis.GetJob(c.Request.Context(), jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	data, err := utils.MarshalJob(job)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode job"})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// adminQueues maps the names accepted by the queue admin endpoints.
+var adminQueues = map[string]redis.Queue{
+	"main":     redis.QueueMain,
+	"free":     redis.QueueFree,
+	"priority": redis.QueuePriority,
+}
+
+// InspectQueues handles GET /admin/queue?limit=N
+// Returns the length and first N job IDs with statuses of every queue.
+func (h *Handler) InspectQueues(c *gin.Context) {
+	limit := int64(defaultQueuePeek)
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 1 || n > maxQueuePeek {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	ctx := c.Request.Context()
+	snapshots := make([]QueueSnapshot, 0, len(adminQueues))
+	for _, queue := range []redis.Queue{redis.QueuePriority, redis.QueueMain, redis.QueueFree} {
+		length, err := h.redis.QueueLength(ctx, queue)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check queue length"})
+			return
+		}
+		ids, err := h.redis.PeekQueue(ctx, queue, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list queue"})
+			return
+		}
+		jobs, err := h.redis.GetJobs(ctx, ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch jobs"})
+			return
+		}
+
+		snapshot := QueueSnapshot{Queue: string(queue), Length: length, Jobs: make([]QueuedJob, 0, len(ids))}
+		for i, id := range ids {
+			entry := QueuedJob{Token: strconv.FormatUint(id, 10)}
+			if job := jobs[i]; job != nil {
+				entry.Status = &models.CheckStatus{ID: job.Status.ID(), Description: job.Status.Description()}
+			}
+			snapshot.Jobs = append(snapshot.Jobs, entry)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	c.JSON(http.StatusOK, gin.H{"queues": snapshots})
+}
+
+// PurgeQueue handles DELETE /admin/queue?queue=main|free|priority
+// Without queue, ?free=true selects the free queue and the main queue is
+// purged otherwise. Queued jobs are dropped from the queue, marked
+// Cancelled and never run; their tokens are returned.
+func (h *Handler) PurgeQueue(c *gin.Context) {
+	name := c.Query("queue")
+	if name == "" {
+		name = "main"
+		if c.Query("free") == "true" {
+			name = "free"
+		}
+	}
+	queue, ok := adminQueues[name]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "queue must be one of main, free, priority"})
+		return
+	}
+
+	// Once IDs come back the queue is already empty, so they are reported
+	// even if some jobs could not be marked cancelled.
+	ids, err := h.redis.PurgeQueue(c.Request.Context(), queue)
+	if err != nil && ids == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to purge queue"})
+		return
+	}
+	tokens := make([]string, len(ids))
+	for i, id := range ids {
+		tokens[i] = strconv.FormatUint(id, 10)
+	}
+	c.JSON(http.StatusOK, gin.H{"queue": string(queue), "purged": len(ids), "tokens": tokens})
+}
+
+// DeleteSubmissions handles DELETE /admin/submissions?before=<unix_ts>&dry_run=true
+// Deletes every job that finished before the timestamp, in seconds. With
+// dry_run it only reports how many would be deleted.
+func (h *Handler) DeleteSubmissions(c *gin.Context) {
+	before, err := strconv.ParseInt(c.Query("before"), 10, 64)
+	if err != nil || before <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before must be a unix timestamp in seconds"})
+		return
+	}
+	if before > math.MaxInt64/int64(time.Second) {
+		before = math.MaxInt64 / int64(time.Second)
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	count, err := h.redis.DeleteJobsBefore(c.Request.Context(), before*int64(time.Second), dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete jobs", "deleted": count})
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{"dry_run": true, "matched": count})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": count})
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var gzipWriters = sync.Pool{
+	New: func() any { return gzip.NewWriter(io.Discard) },
+}
+
+// decompressRequest transparently decodes gzip and deflate request bodies so
+// handlers always see plain JSON. The decoded stream is capped at
+// maxBodyBytes so a small compressed body cannot expand without bound.
+func (h *Handler) decompressRequest(c *gin.Context) {
+	var (
+		body io.ReadCloser
+		err  error
+	)
+	switch strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding"))) {
+	case "", "identity":
+		c.Next()
+		return
+	case "gzip":
+		body, err = gzip.NewReader(c.Request.Body)
+	case "deflate":
+		body, err = zlib.NewReader(c.Request.Body)
+	default:
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported content encoding"})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid compressed body"})
+		return
+	}
+	defer body.Close()
+	c.Request.Body = body
+	if h.maxBodyBytes > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, body, h.maxBodyBytes)
+	}
+	c.Request.Header.Del("Content-Encoding")
+	c.Request.Header.Del("Content-Length")
+	c.Request.ContentLength = -1
+	c.Next()
+}
+
+// compressResponse gzips response bodies for clients that accept it. The
+// metrics endpoint is skipped because promhttp compresses on its own.
+func compressResponse(c *gin.Context) {
+	if c.Request.Method == http.MethodHead || c.FullPath() == "/metrics" || !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+		c.Next()
+		return
+	}
+	zw := gzipWriters.Get().(*gzip.Writer)
+	zw.Reset(c.Writer)
+	defer func() {
+		zw.Close()
+		zw.Reset(io.Discard)
+		gzipWriters.Put(zw)
+	}()
+
+	c.Header("Content-Encoding", "gzip")
+	c.Header("Vary", "Accept-Encoding")
+	c.Writer = &gzipResponseWriter{ResponseWriter: c.Writer, zw: zw}
+	c.Next()
+	c.Writer.Header().Del("Content-Length")
+}
+
+// gzipResponseWriter compresses everything written through it.
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	zw *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	g.Header().Del("Content-Length")
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func (g *gzipResponseWriter) Write(data []byte) (int, error) {
+	g.Header().Del("Content-Length")
+	return g.zw.Write(data)
+}
+
+func (g *gzipResponseWriter) WriteString(s string) (int, error) {
+	return g.Write([]byte(s))
+}
+
+// Flush pushes buffered compressed data to the client so streamed responses
+// keep working.
+func (g *gzipResponseWriter) Flush() {
+	_ = g.zw.Flush()
+	g.ResponseWriter.Flush()
+}
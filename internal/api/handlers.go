@@ -1,57 +1,204 @@
 package api
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"flash-go/internal/core"
+	"flash-go/internal/isolate"
+	"flash-go/internal/metrics"
 	"flash-go/internal/models"
 	"flash-go/internal/redis"
 	"flash-go/internal/utils"
+	"flash-go/internal/version"
+	"flash-go/internal/worker"
 
 	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
 	"github.com/sirupsen/logrus"
 )
 
+// Config holds handler options.
+type Config struct {
+	QueueLengthLimit  int
+	WorkerConcurrency int
+	UseBoxPool        bool
+	// TimestampUnit controls how job timestamps are reported.
+	TimestampUnit core.TimestampUnit
+	// Judge0FallbackLanguage is used for unmapped Judge0 language IDs when set.
+	Judge0FallbackLanguage string
+	// IncludeSourceHash stores a short hash of each submission's source so
+	// responses carry it.
+	IncludeSourceHash bool
+	// DisableFreeQueue ignores the free flag and routes every job to the main
+	// queue, leaving the free queue out of health and capacity checks.
+	DisableFreeQueue bool
+	// ValidateEntrypoint rejects submissions whose entrypoint file is empty.
+	ValidateEntrypoint bool
+	// AdminToken guards the /admin routes; they are disabled when empty.
+	AdminToken string
+	// MaxSourceBytes caps the decoded size of a submission's source code;
+	// 0 disables the check.
+	MaxSourceBytes int
+	// MaxBatchSize caps the number of submissions in one batch request;
+	// 0 disables the check.
+	MaxBatchSize int
+	// MaxResultTTL caps the result_ttl_seconds a submission may request.
+	MaxResultTTL time.Duration
+	// IncludeDebug adds the box and worker that ran a job to check responses.
+	IncludeDebug bool
+	// DisableCompression turns off gzip/deflate request decoding and gzip
+	// responses.
+	DisableCompression bool
+	// MaxBodyBytes caps the decompressed size of a request body; 0 disables
+	// the check.
+	MaxBodyBytes int64
+	// RateLimitPerMinute caps submissions per client per minute; 0 disables it.
+	RateLimitPerMinute int
+	// MaxInFlightPerKey caps the queued and running jobs of one API key, or
+	// of one client IP when the request has no validated key; 0 disables it.
+	MaxInFlightPerKey int
+	// IdempotencyTTL is how long an Idempotency-Key maps to the job it
+	// created.
+	IdempotencyTTL time.Duration
+	// Executor, when set, must have a warm box pool for readiness.
+	Executor *isolate.Executor
+	// APIKeys, when non-empty, are the X-Auth-Token values accepted by the
+	// public API. Health and metrics stay open; admin routes use AdminToken.
+	APIKeys []string
+}
+
 type Handler struct {
-	redis             *redis.Client
-	queueLengthLimit  int64
-	workerConcurrency int
-	useBoxPool        bool
+	redis                  *redis.Client
+	queueLengthLimit       int64
+	workerConcurrency      int
+	useBoxPool             bool
+	timestampUnit          core.TimestampUnit
+	judge0FallbackLanguage string
+	includeSourceHash      bool
+	disableFreeQueue       bool
+	validateEntrypoint     bool
+	adminToken             string
+	maxSourceBytes         int
+	maxBatchSize           int
+	maxResultTTL           time.Duration
+	includeDebug           bool
+	disableCompression     bool
+	maxBodyBytes           int64
+	rateLimitPerMinute     int
+	maxInFlightPerKey      int
+	idempotencyTTL         time.Duration
+	apiKeys                map[[sha256.Size]byte]struct{}
+	executor               *isolate.Executor
 }
 
 type preparedSubmission struct {
-	sourceCode     string
-	stdin          string
-	expectedOutput string
-	lang           models.Language
-	settings       models.ExecutionSettings
+	sourceCode      string
+	stdin           string
+	expectedOutput  string
+	compilerOptions string
+	lang            models.Language
+	settings        models.ExecutionSettings
 }
 
-func NewHandler(redisClient *redis.Client, queueLengthLimit int, workerConcurrency int, useBoxPool bool) *Handler {
+func NewHandler(redisClient *redis.Client, cfg Config) *Handler {
 	return &Handler{
-		redis:             redisClient,
-		queueLengthLimit:  int64(queueLengthLimit),
-		workerConcurrency: workerConcurrency,
-		useBoxPool:        useBoxPool,
+		redis:                  redisClient,
+		queueLengthLimit:       int64(cfg.QueueLengthLimit),
+		workerConcurrency:      cfg.WorkerConcurrency,
+		useBoxPool:             cfg.UseBoxPool,
+		timestampUnit:          cfg.TimestampUnit,
+		judge0FallbackLanguage: cfg.Judge0FallbackLanguage,
+		includeSourceHash:      cfg.IncludeSourceHash,
+		disableFreeQueue:       cfg.DisableFreeQueue,
+		validateEntrypoint:     cfg.ValidateEntrypoint,
+		adminToken:             cfg.AdminToken,
+		maxSourceBytes:         cfg.MaxSourceBytes,
+		maxBatchSize:           cfg.MaxBatchSize,
+		maxResultTTL:           cfg.MaxResultTTL,
+		includeDebug:           cfg.IncludeDebug,
+		disableCompression:     cfg.DisableCompression,
+		maxBodyBytes:           cfg.MaxBodyBytes,
+		rateLimitPerMinute:     cfg.RateLimitPerMinute,
+		maxInFlightPerKey:      cfg.MaxInFlightPerKey,
+		idempotencyTTL:         cfg.IdempotencyTTL,
+		apiKeys:                hashAPIKeys(cfg.APIKeys),
+		executor:               cfg.Executor,
 	}
 }
 
 func RegisterRoutes(router *gin.Engine, handler *Handler) {
-	router.POST("/create", handler.Create)
-	router.GET("/check/:job_id", handler.Check)
+	router.Use(requestLogger)
+	if !handler.disableCompression {
+		router.Use(handler.decompressRequest, compressResponse)
+	}
 	router.GET("/health", handler.Health)
-	router.POST("/submissions/batch", handler.SubmitBatch)
-	router.GET("/submissions/batch", handler.GetBatch)
+	router.GET("/health/live", handler.Live)
+	router.GET("/health/ready", handler.Health)
+	router.GET("/metrics", handler.Metrics)
+	router.GET("/version", handler.Version)
+	router.GET("/stats", handler.Stats)
+
+	authed := router.Group("", handler.requireAPIKey)
+	authed.POST("/create", handler.rateLimit, handler.Create)
+	authed.GET("/check/:job_id", handler.Check)
+	authed.GET("/languages", handler.Languages)
+	authed.POST("/submissions/batch", handler.rateLimit, handler.SubmitBatch)
+	authed.GET("/submissions/batch", handler.GetBatch)
+	authed.GET("/submissions/batch/summary", handler.GetBatchSummary)
+	authed.GET("/submissions/:token", handler.GetSubmission)
+	authed.GET("/submissions/:token/stream", handler.StreamSubmission)
+
+	admin := router.Group("/admin", handler.requireAdmin)
+	admin.GET("/dead-letters", handler.ListDeadLetters)
+	admin.POST("/dead-letters/:token/requeue", handler.RequeueDeadLetter)
+	admin.GET("/submissions/:token/raw", handler.GetRawJob)
+	admin.DELETE("/submissions", handler.DeleteSubmissions)
+	admin.GET("/queue", handler.InspectQueues)
+	admin.DELETE("/queue", handler.PurgeQueue)
+}
+
+// queueFor picks the queue a submission is routed to. The free flag is
+// ignored when the free queue is disabled.
+func (h *Handler) queueFor(free, priority bool) redis.Queue {
+	switch {
+	case priority:
+		return redis.QueuePriority
+	case free && !h.disableFreeQueue:
+		return redis.QueueFree
+	default:
+		return redis.QueueMain
+	}
 }
 
-func (h *Handler) hasQueueCapacity(ctx *gin.Context, free bool, incoming int) (bool, error) {
+// clampResultTTL bounds a requested result TTL in seconds to
+// [1, maxResultTTL].
+func (h *Handler) clampResultTTL(seconds int64) int64 {
+	seconds = max(seconds, 1)
+	if limit := int64(h.maxResultTTL / time.Second); limit > 0 && seconds > limit {
+		seconds = limit
+	}
+	return seconds
+}
+
+// sourceTooLarge reports whether decoded source code exceeds the configured limit.
+func (h *Handler) sourceTooLarge(code string) bool {
+	return h.maxSourceBytes > 0 && len(code) > h.maxSourceBytes
+}
+
+func (h *Handler) hasQueueCapacity(ctx *gin.Context, queue redis.Queue, incoming int) (bool, error) {
 	if h.queueLengthLimit <= 0 {
 		return true, nil
 	}
-	length, err := h.redis.QueueLength(ctx.Request.Context(), free)
+	length, err := h.redis.QueueLength(ctx.Request.Context(), queue)
 	if err != nil {
 		logrus.WithError(err).Error("failed to check queue length")
 		return false, err
@@ -59,15 +206,41 @@ func (h *Handler) hasQueueCapacity(ctx *gin.Context, free bool, incoming int) (b
 	return length+int64(incoming) <= h.queueLengthLimit, nil
 }
 
+// validCallbackURL reports whether raw is an absolute http(s) URL.
+func validCallbackURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// bindError reports a request body that could not be decoded, using 413 when
+// it ran past the body size limit.
+func bindError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+}
+
 // Create enqueues a new job.
 func (h *Handler) Create(c *gin.Context) {
 	var req models.CreateJobRequest
 	if err := utils.BindJSONFast(c, &req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		bindError(c, err)
 		return
 	}
 
-	if ok, err := h.hasQueueCapacity(c, req.Free, 1); err != nil {
+	if req.Free && req.Priority && !h.disableFreeQueue {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "free and priority are mutually exclusive"})
+		return
+	}
+	queue := h.queueFor(req.Free, req.Priority)
+
+	if ok, err := h.hasQueueCapacity(c, queue, 1); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check queue length"})
 		return
 	} else if !ok {
@@ -75,13 +248,90 @@ func (h *Handler) Create(c *gin.Context) {
 		return
 	}
 
-	lang, ok := core.LanguageFor(req.Language)
+	lang, ok := core.LanguageFor(req.Language, req.LanguageVersion)
 	if !ok {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported language"})
 		return
 	}
+	if req.SourceFileOverride != "" {
+		overridden, err := core.WithSourceFile(lang, req.SourceFileOverride)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		lang = overridden
+	}
+	if req.ArtifactName != "" {
+		renamed, err := core.WithArtifact(lang, req.ArtifactName)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		lang = renamed
+	}
+	if req.StrictMode {
+		strict, err := core.WithStrictMode(lang)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		lang = strict
+	}
+	var binary []byte
+	if req.Binary != "" {
+		if !req.RunOnly {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "binary requires run_only"})
+			return
+		}
+		decoded, err := base64.StdEncoding.DecodeString(req.Binary)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid base64 in binary"})
+			return
+		}
+		binary = decoded
+	}
+	additionalFiles := make(map[string]string, len(req.AdditionalFiles))
+	for name, encoded := range req.AdditionalFiles {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid base64 in additional file %s", name)})
+			return
+		}
+		additionalFiles[name] = string(decoded)
+	}
+	// The entrypoint may be uploaded as one of the files instead of as code.
+	entryFromFiles := false
+	if entry, ok := additionalFiles[lang.SourceFile]; ok && req.Code == "" {
+		req.Code = entry
+		delete(additionalFiles, lang.SourceFile)
+		entryFromFiles = true
+	}
+	if err := core.ValidateAdditionalFiles(lang, additionalFiles); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" && !req.RunOnly {
+		// Only an entrypoint that was actually uploaded may be left empty,
+		// and only when VALIDATE_ENTRYPOINT is off.
+		switch {
+		case entryFromFiles && h.validateEntrypoint:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("entrypoint %s is empty", lang.SourceFile)})
+			return
+		case entryFromFiles:
+		case len(additionalFiles) == 0:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "source code is empty"})
+			return
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("entrypoint %s is missing", lang.SourceFile)})
+			return
+		}
+	}
+	if h.sourceTooLarge(req.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("source code exceeds %d bytes", h.maxSourceBytes)})
+		return
+	}
 
-	settings := core.DefaultExecutionSettings()
+	settings := core.SettingsFor(lang)
 	if req.TimeLimit != nil {
 		settings.CPUTimeLimit = *req.TimeLimit
 	}
@@ -91,19 +341,179 @@ func (h *Handler) Create(c *gin.Context) {
 	if req.StackLimit != nil {
 		settings.StackLimit = *req.StackLimit
 	}
+	if req.WallTimeLimit != nil {
+		if *req.WallTimeLimit <= 0 || math.IsNaN(*req.WallTimeLimit) || math.IsInf(*req.WallTimeLimit, 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "wall_time_limit must be positive"})
+			return
+		}
+		if *req.WallTimeLimit > settings.MaxWallTimeLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "wall_time_limit exceeds maximum"})
+			return
+		}
+		settings.WallTimeLimit = *req.WallTimeLimit
+	}
+	if req.MaxProcesses != nil {
+		if *req.MaxProcesses > core.MaxProcessesLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_processes exceeds maximum"})
+			return
+		}
+		settings.MaxProcesses = *req.MaxProcesses
+	}
+	if req.StressRuns != nil {
+		if *req.StressRuns > core.MaxStressRuns {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stress_runs exceeds maximum"})
+			return
+		}
+		settings.StressRuns = *req.StressRuns
+	}
+	if req.NumberOfRuns != nil {
+		if *req.NumberOfRuns > core.MaxNumberOfRuns {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "number_of_runs exceeds maximum"})
+			return
+		}
+		settings.NumberOfRuns = *req.NumberOfRuns
+	}
+	if req.DiskQuotaKB != nil {
+		if *req.DiskQuotaKB > core.MaxDiskQuotaKB {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "disk_quota_kb exceeds maximum"})
+			return
+		}
+		settings.DiskQuotaKB = *req.DiskQuotaKB
+	}
+	if err := utils.ValidateComparisonMode(req.ComparisonMode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	settings.ComparisonMode = req.ComparisonMode
+	if req.FloatTolerance != nil {
+		if *req.FloatTolerance < 0 || math.IsNaN(*req.FloatTolerance) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "float_tolerance must be non-negative"})
+			return
+		}
+		settings.FloatTolerance = *req.FloatTolerance
+	}
+	settings.NormalizeLineEndings = req.NormalizeLineEndings
+	settings.ReportPresentationError = req.ReportPresentationError
+	if err := core.ValidateInputMode(req.InputMode, lang, additionalFiles); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	settings.InputMode = req.InputMode
+	settings.CompileOnly = req.CompileOnly
+	if req.RunOnly {
+		if settings.CompileOnly {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "run_only cannot be combined with compile_only"})
+			return
+		}
+		if err := core.ValidateRunOnly(lang, binary, settings.MaxFileSize); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		settings.RunOnly = true
+	}
 
-	job := core.NewJob(req.Code, req.Input, req.Expected, lang, settings)
+	if err := utils.ValidateCompilerOptions(req.CompilerOptions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var checker *models.Checker
+	if req.Checker != nil {
+		var err error
+		if checker, err = core.NewChecker(*req.Checker); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if settings.StressRuns > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stress_runs cannot be combined with a checker"})
+			return
+		}
+		if settings.CompileOnly {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "compile_only cannot be combined with a checker"})
+			return
+		}
+		if checker.Interactive && settings.NumberOfRuns > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "number_of_runs cannot be combined with an interactive checker"})
+			return
+		}
+		if checker.Interactive && settings.InputMode == models.InputModeFile {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "input_mode file cannot be combined with an interactive checker"})
+			return
+		}
+	}
+
+	if err := core.ValidateEnv(req.Env); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.CallbackURL != "" && !validCallbackURL(req.CallbackURL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid callback_url"})
+		return
+	}
 
-	var err error
-	if req.Free {
-		err = h.redis.CreateFreeJob(c.Request.Context(), &job)
-	} else {
-		err = h.redis.CreateJob(c.Request.Context(), &job)
+	job := core.NewJob(req.Code, req.Input, req.Expected, lang, settings)
+	job.CorrelationID = requestID(c)
+	job.CallbackURL = req.CallbackURL
+	job.Priority = req.Priority
+	job.CompilerOptions = req.CompilerOptions
+	if len(additionalFiles) > 0 {
+		job.AdditionalFiles = additionalFiles
+	}
+	if len(req.Env) > 0 {
+		job.Env = req.Env
+	}
+	job.Binary = binary
+	if req.ResultTTLSeconds != nil {
+		job.ResultTTL = h.clampResultTTL(*req.ResultTTLSeconds)
+	}
+	job.Checker = checker
+	if h.includeSourceHash {
+		job.SourceHash = core.SourceHash(job.SourceCode)
 	}
+
+	idemKey, err := idempotencyKey(c)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if idemKey != "" {
+		existing, claimed, err := h.redis.ClaimIdempotencyKey(c.Request.Context(), idemKey, job.ID, h.idempotencyTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check idempotency key"})
+			return
+		}
+		if !claimed {
+			c.Header(idempotentReplayHeader, "true")
+			c.JSON(http.StatusOK, models.CreateJobResponse{
+				Status: "created",
+				ID:     strconv.FormatUint(existing, 10),
+			})
+			return
+		}
+	}
+
+	inFlight := inFlightKey(c)
+	held, ok := h.acquireInFlight(c, inFlight, 1)
+	if !ok {
+		if idemKey != "" {
+			_ = h.redis.ReleaseIdempotencyKey(c.Request.Context(), idemKey)
+		}
+		return
+	}
+	if held > 0 {
+		job.InFlightKey = inFlight
+	}
+
+	if err := h.redis.Enqueue(c.Request.Context(), &job, queue); err != nil {
+		if idemKey != "" {
+			_ = h.redis.ReleaseIdempotencyKey(c.Request.Context(), idemKey)
+		}
+		h.releaseInFlight(c, inFlight, held)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enqueue job"})
 		return
 	}
+	metrics.JobsEnqueued.WithLabelValues(lang.Name).Inc()
 
 	c.JSON(http.StatusOK, models.CreateJobResponse{
 		Status: "created",
@@ -132,49 +542,128 @@ func (h *Handler) Check(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.CheckResponse{
-		CreatedAt:     job.CreatedAt,
-		StartedAt:     job.StartedAt,
-		FinishedAt:    job.FinishedAt,
-		Stdout:        job.Output.Stdout,
-		Time:          job.Output.Time,
-		Memory:        job.Output.Memory,
-		Stderr:        job.Output.Stderr,
-		Token:         job.ID,
-		CompileOutput: job.Output.CompileOutput,
-		Message:       job.Output.Message,
-		Status: models.CheckStatus{
-			ID:          job.Status.ID(),
-			Description: job.Status.Description(),
-		},
-	})
+	response := core.NewCheckResponse(job, h.timestampUnit)
+	if h.includeDebug {
+		response.Debug = &models.JobDebug{BoxID: job.Output.BoxID, WorkerID: job.Output.WorkerID}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Live reports that the process is up. It checks no dependencies so a slow
+// Redis does not get the pod restarted.
+func (h *Handler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
-// Health returns service health with queue stats and jobs run count.
+// Health reports readiness with queue stats: Redis must be reachable, isolate
+// available with a warm box pool, the worker circuit breaker closed and the
+// main queue under its limit. It backs /health and /health/ready.
 func (h *Handler) Health(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	mainQueueLength, err := h.redis.QueueLength(ctx, false)
+	mainQueueLength, err := h.redis.QueueLength(ctx, redis.QueueMain)
 	if err != nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "error": "main queue length check failed"})
 		return
 	}
-	freeQueueLength, err := h.redis.QueueLength(ctx, true)
+	priorityQueueLength, err := h.redis.QueueLength(ctx, redis.QueuePriority)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "error": "free queue length check failed"})
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "error": "priority queue length check failed"})
 		return
 	}
 
+	poolReady := h.executor == nil || h.executor.PoolReady()
+	status, code := "ok", http.StatusOK
+	if !isolate.Available() || !poolReady || !worker.Ready() || (h.queueLengthLimit > 0 && mainQueueLength >= h.queueLengthLimit) {
+		status, code = "error", http.StatusServiceUnavailable
+	}
+
 	response := gin.H{
-		"status":              "ok",
-		"main_queue_length":   mainQueueLength,
-		"main_queue_limit":    h.queueLengthLimit,
-		"free_queue_length":   freeQueueLength,
-		"free_queue_limit":    h.queueLengthLimit,
-		"worker_concurrency":  h.workerConcurrency,
-		"use_box_pool":        h.useBoxPool,
-		"main_queue_available": h.queueLengthLimit - mainQueueLength,
-		"free_queue_available": h.queueLengthLimit - freeQueueLength,
+		"status":                   status,
+		"isolate_available":        isolate.Available(),
+		"circuit_open":             !worker.Ready(),
+		"pool_ready":               poolReady,
+		"cgroups":                  isolate.Cgroups(),
+		"main_queue_length":        mainQueueLength,
+		"main_queue_limit":         h.queueLengthLimit,
+		"priority_queue_length":    priorityQueueLength,
+		"priority_queue_limit":     h.queueLengthLimit,
+		"worker_concurrency":       h.workerConcurrency,
+		"use_box_pool":             h.useBoxPool,
+		"main_queue_available":     h.queueLengthLimit - mainQueueLength,
+		"priority_queue_available": h.queueLengthLimit - priorityQueueLength,
+	}
+
+	if !h.disableFreeQueue {
+		freeQueueLength, err := h.redis.QueueLength(ctx, redis.QueueFree)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "error": "free queue length check failed"})
+			return
+		}
+		response["free_queue_length"] = freeQueueLength
+		response["free_queue_limit"] = h.queueLengthLimit
+		response["free_queue_available"] = h.queueLengthLimit - freeQueueLength
+	}
+
+	c.JSON(code, response)
+}
+
+// Stats reports worker throughput: jobs processed, execution times and
+// active jobs, per worker and per language.
+func (h *Handler) Stats(c *gin.Context) {
+	c.JSON(http.StatusOK, worker.CurrentStats())
+}
+
+// Version reports build information and the isolate setup.
+func (h *Handler) Version(c *gin.Context) {
+	cgroupMode := "unknown"
+	if status := isolate.Cgroups(); status != nil {
+		cgroupMode = status.Version
+		if !status.Enabled {
+			cgroupMode = "disabled"
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"commit":          version.Commit,
+		"build_time":      version.BuildTime,
+		"go_version":      version.GoVersion(),
+		"isolate_version": isolate.Version(),
+		"cgroup_mode":     cgroupMode,
+	})
+}
+
+// Metrics serves Prometheus metrics, refreshing queue depth gauges first.
+func (h *Handler) Metrics(c *gin.Context) {
+	ctx := c.Request.Context()
+	for _, queue := range []redis.Queue{redis.QueueMain, redis.QueueFree, redis.QueuePriority} {
+		if queue == redis.QueueFree && h.disableFreeQueue {
+			continue
+		}
+		length, err := h.redis.QueueLength(ctx, queue)
+		if err != nil {
+			continue
+		}
+		metrics.QueueDepth.WithLabelValues(string(queue)).Set(float64(length))
+	}
+	metrics.Handler().ServeHTTP(c.Writer, c.Request)
+}
+
+// Languages lists the supported runtimes with their Judge0 IDs where mapped.
+func (h *Handler) Languages(c *gin.Context) {
+	langs := core.Languages()
+	response := make([]models.LanguageInfo, 0, len(langs))
+	for _, lang := range langs {
+		info := models.LanguageInfo{
+			Name:       lang.Name,
+			SourceFile: lang.SourceFile,
+			IsCompiled: lang.IsCompiled,
+			Judge0IDs:  utils.Judge0LanguageIDsFor(lang.Name),
+			Versions:   core.LanguageVersions(lang.Name),
+		}
+		if len(info.Judge0IDs) > 0 {
+			info.ID = info.Judge0IDs[0]
+		}
+		response = append(response, info)
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -187,7 +676,7 @@ func (h *Handler) SubmitBatch(c *gin.Context) {
 
 	var req models.Judge0BatchSubmissionRequest
 	if err := utils.BindJSONFast(c, &req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		bindError(c, err)
 		return
 	}
 
@@ -195,15 +684,16 @@ func (h *Handler) SubmitBatch(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "submissions array cannot be empty"})
 		return
 	}
-
-	if ok, err := h.hasQueueCapacity(c,req.Free, len(req.Submissions)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check queue length"})
+	if h.maxBatchSize > 0 && len(req.Submissions) > h.maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("batch exceeds %d submissions", h.maxBatchSize)})
 		return
-	} else if !ok {
-		c.JSON(http.StatusTooManyRequests, gin.H{"error": "queue limit reached"})
+	}
+
+	if req.Free && req.Priority && !h.disableFreeQueue {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "free and priority are mutually exclusive"})
 		return
 	}
-	
+	queue := h.queueFor(req.Free, req.Priority)
 
 	prepared := make([]preparedSubmission, 0, len(req.Submissions))
 
@@ -238,20 +728,32 @@ func (h *Handler) SubmitBatch(c *gin.Context) {
 				expectedOutput = string(decoded)
 			}
 		}
+		if strings.TrimSpace(sourceCode) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "source code is empty"})
+			return
+		}
+		if h.sourceTooLarge(sourceCode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("source_code exceeds %d bytes", h.maxSourceBytes)})
+			return
+		}
 
 		langName, ok := utils.Judge0LanguageIDToName(sub.LanguageID)
+		if !ok && h.judge0FallbackLanguage != "" {
+			logrus.WithField("language_id", sub.LanguageID).Warn("unknown Judge0 language_id, using fallback language")
+			langName, ok = h.judge0FallbackLanguage, true
+		}
 		if !ok {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported language_id"})
 			return
 		}
 
-		lang, ok := core.LanguageFor(langName)
+		lang, ok := core.LanguageFor(langName, "")
 		if !ok {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported language"})
 			return
 		}
 
-		settings := core.DefaultExecutionSettings()
+		settings := core.SettingsFor(lang)
 		if sub.CPUTimeLimit > 0 {
 			settings.CPUTimeLimit = sub.CPUTimeLimit
 		}
@@ -261,29 +763,97 @@ func (h *Handler) SubmitBatch(c *gin.Context) {
 		if sub.MaxProcessesAndOrThreads > 0 {
 			settings.MaxProcesses = uint32(sub.MaxProcessesAndOrThreads)
 		}
+		if sub.TrimWhitespace != nil {
+			settings.ComparisonMode = models.ComparisonExact
+			if *sub.TrimWhitespace {
+				settings.ComparisonMode = models.ComparisonTrailingTrim
+			}
+		}
+		if sub.ComparisonMode != "" {
+			if err := utils.ValidateComparisonMode(sub.ComparisonMode); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			settings.ComparisonMode = sub.ComparisonMode
+		}
+
+		if sub.NumberOfRuns > core.MaxNumberOfRuns {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "number_of_runs exceeds maximum"})
+			return
+		}
+		settings.NumberOfRuns = sub.NumberOfRuns
+
+		if err := utils.ValidateCompilerOptions(sub.CompilerOptions); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		prepared = append(prepared, preparedSubmission{
-			sourceCode:     sourceCode,
-			stdin:          stdin,
-			expectedOutput: expectedOutput,
-			lang:           lang,
-			settings:       settings,
+			sourceCode:      sourceCode,
+			stdin:           stdin,
+			expectedOutput:  expectedOutput,
+			compilerOptions: sub.CompilerOptions,
+			lang:            lang,
+			settings:        settings,
 		})
 	}
 
+	inFlight := inFlightKey(c)
+	held, ok := h.acquireInFlight(c, inFlight, len(prepared))
+	if !ok {
+		return
+	}
+	enqueued := 0
+	defer func() { h.releaseInFlight(c, inFlight, held-enqueued) }()
+
+	// Capacity is re-checked before every push, so concurrent batches compete
+	// for the remaining room entry by entry; entries that no longer fit are
+	// reported, not enqueued.
 	responses := make([]models.Judge0SubmissionResponse, 0, len(prepared))
-	for _, sub := range prepared {
+	for i, sub := range prepared {
+		fits, err := h.hasQueueCapacity(c, queue, 1)
+		if err != nil || !fits {
+			status, message := http.StatusTooManyRequests, "queue limit reached"
+			if err != nil {
+				status, message = http.StatusInternalServerError, "failed to check queue length"
+			}
+			if i == 0 {
+				c.JSON(status, gin.H{"error": message})
+				return
+			}
+			for range prepared[i:] {
+				responses = append(responses, models.Judge0SubmissionResponse{Error: message})
+			}
+			break
+		}
+
 		job := core.NewJob(sub.sourceCode, sub.stdin, sub.expectedOutput, sub.lang, sub.settings)
-		var err error
-		if req.Free {
-			err = h.redis.CreateFreeJob(c.Request.Context(), &job)
-		} else {
-			err = h.redis.CreateJob(c.Request.Context(), &job)
+		job.CorrelationID = requestID(c) + "-" + strconv.Itoa(i)
+		job.Priority = req.Priority
+		job.CompilerOptions = sub.compilerOptions
+		if h.includeSourceHash {
+			job.SourceHash = core.SourceHash(job.SourceCode)
 		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enqueue job"})
-			return
+		if held > 0 {
+			job.InFlightKey = inFlight
+		}
+		if err := h.redis.Enqueue(c.Request.Context(), &job, queue); err != nil {
+			logrus.WithError(err).WithField("correlation_id", job.CorrelationID).Error("failed to enqueue batch submission")
+			if i == 0 {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enqueue job"})
+				return
+			}
+			// Earlier entries are already queued, so their tokens must still
+			// reach the client; the rest are reported as failed.
+			for range prepared[i:] {
+				responses = append(responses, models.Judge0SubmissionResponse{Error: "failed to enqueue job"})
+			}
+			break
+		}
+		if held > 0 {
+			enqueued++
 		}
+		metrics.JobsEnqueued.WithLabelValues(sub.lang.Name).Inc()
 
 		responses = append(responses, models.Judge0SubmissionResponse{
 			Token: strconv.FormatUint(job.ID, 10),
@@ -293,19 +863,19 @@ func (h *Handler) SubmitBatch(c *gin.Context) {
 	c.JSON(http.StatusCreated, responses)
 }
 
-// GetBatch handles GET /submissions/batch?tokens={tokens}&base64_encoded=false
-// Retrieves the status and results of batch submissions by tokens.
-func (h *Handler) GetBatch(c *gin.Context) {
+// parseTokens reads the comma-separated tokens query parameter. On failure it
+// writes a 400 response and returns false.
+func parseTokens(c *gin.Context) ([]uint64, bool) {
 	tokensStr := c.Query("tokens")
 	if tokensStr == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "tokens parameter is required"})
-		return
+		return nil, false
 	}
 
 	tokenStrs := strings.Split(tokensStr, ",")
 	if len(tokenStrs) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one token is required"})
-		return
+		return nil, false
 	}
 
 	jobIDs := make([]uint64, 0, len(tokenStrs))
@@ -317,13 +887,24 @@ func (h *Handler) GetBatch(c *gin.Context) {
 		jobID, err := strconv.ParseUint(tokenStr, 10, 64)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token format"})
-			return
+			return nil, false
 		}
 		jobIDs = append(jobIDs, jobID)
 	}
 
 	if len(jobIDs) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no valid tokens provided"})
+		return nil, false
+	}
+	return jobIDs, true
+}
+
+// GetBatch handles GET /submissions/batch?tokens={tokens}&base64_encoded=false&fields=...
+// Retrieves the status and results of batch submissions by tokens. When fields
+// is set, each submission only includes the listed keys.
+func (h *Handler) GetBatch(c *gin.Context) {
+	jobIDs, ok := parseTokens(c)
+	if !ok {
 		return
 	}
 
@@ -334,6 +915,7 @@ func (h *Handler) GetBatch(c *gin.Context) {
 		return
 	}
 
+	base64Encoded := c.Query("base64_encoded") == "true"
 	submissions := make([]*models.Judge0SubmissionDetails, 0, len(jobIDs))
 	for i := range jobIDs {
 		var job *models.Job
@@ -341,49 +923,76 @@ func (h *Handler) GetBatch(c *gin.Context) {
 			job = jobs[i]
 		}
 		if job == nil {
-			submissions = append(submissions, nil)
+			submissions = append(submissions, missingDetails(jobIDs[i], base64Encoded))
 			continue
 		}
 
-		details := models.Judge0SubmissionDetails{
-			Token: strconv.FormatUint(job.ID, 10),
-			Status: models.Judge0Status{
-				ID:          job.Status.ID(),
-				Description: job.Status.Description(),
-			},
-			CreatedAt:  job.CreatedAt,
-			StartedAt:  job.StartedAt,
-			FinishedAt: job.FinishedAt,
-		}
+		submissions = append(submissions, h.judge0Details(job, base64Encoded))
+	}
 
-		if job.Output.Stdout != "" {
-			details.Stdout = &job.Output.Stdout
-		}
-		if job.Output.Stderr != "" {
-			details.Stderr = &job.Output.Stderr
+	fields := parseFields(c.Query("fields"))
+	if fields == nil {
+		c.JSON(http.StatusOK, models.Judge0BatchResponse{
+			Submissions: submissions,
+		})
+		return
+	}
+
+	filtered := make([]map[string]json.RawMessage, len(submissions))
+	for i, details := range submissions {
+		if filtered[i], err = selectFields(details, fields); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode submissions"})
+			return
 		}
-		if job.Output.CompileOutput != "" {
-			details.CompileOutput = &job.Output.CompileOutput
+	}
+	c.JSON(http.StatusOK, gin.H{"submissions": filtered})
+}
+
+// GetBatchSummary handles GET /submissions/batch/summary?tokens={tokens}
+// Returns an aggregate verdict for a batch instead of per-token details.
+func (h *Handler) GetBatchSummary(c *gin.Context) {
+	jobIDs, ok := parseTokens(c)
+	if !ok {
+		return
+	}
+
+	jobs, err := h.redis.GetJobs(c.Request.Context(), jobIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch jobs"})
+		return
+	}
+
+	summary := models.BatchSummary{Total: len(jobIDs), StatusCounts: map[string]int{}}
+	for _, job := range jobs {
+		if job == nil {
+			summary.Missing++
+			continue
 		}
-		if job.Output.Message != "" {
-			details.Message = &job.Output.Message
-		} else if job.Status.Kind == models.StatusCompilationError && job.Output.CompileOutput != "" {
-			message := job.Output.CompileOutput
-			details.Message = &message
+		summary.StatusCounts[job.Status.Description()]++
+		if !job.Status.IsTerminal() {
+			summary.Pending++
+			continue
 		}
-		if job.Output.Time > 0 {
-			timeStr := strconv.FormatFloat(job.Output.Time, 'f', -1, 64)
-			details.Time = &timeStr
+
+		summary.Finished++
+		summary.TotalTime += job.Output.Time
+		if job.Output.Memory > summary.MaxMemory {
+			summary.MaxMemory = job.Output.Memory
 		}
-		if job.Output.Memory > 0 {
-			memory := int(job.Output.Memory)
-			details.Memory = &memory
+		if job.Status.Kind == models.StatusAccepted {
+			summary.Accepted++
+		} else if summary.FirstFailure == nil {
+			summary.FirstFailure = &models.BatchFailure{
+				Token: strconv.FormatUint(job.ID, 10),
+				Status: models.Judge0Status{
+					ID:          job.Status.ID(),
+					Description: job.Status.Description(),
+				},
+			}
 		}
-
-		submissions = append(submissions, &details)
 	}
+	// Missing tokens never finish, so a batch with any is never done.
+	summary.Done = summary.Finished == summary.Total
 
-	c.JSON(http.StatusOK, models.Judge0BatchResponse{
-		Submissions: submissions,
-	})
+	c.JSON(http.StatusOK, summary)
 }
@@ -0,0 +1,480 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"flash-go/internal/models"
+	"flash-go/internal/redis"
+	"flash-go/internal/utils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestServer returns a router wired to a handler backed by an in-memory
+// Redis.
+func newTestServer(t *testing.T, cfg Config) (*gin.Engine, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rc, err := redis.New("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	RegisterRoutes(router, NewHandler(rc, cfg))
+	return router, rc
+}
+
+func do(router http.Handler, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+// checkCreate posts each body to /create and checks the response status.
+func checkCreate(t *testing.T, router http.Handler, tests []struct {
+	name string
+	body string
+	want int
+}) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(router, http.MethodPost, "/create", tt.body, nil)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestCreateRejectsEmptySource(t *testing.T) {
+	router, _ := newTestServer(t, Config{})
+	checkCreate(t, router, []struct {
+		name string
+		body string
+		want int
+	}{
+		{"empty code", `{"language": "python", "code": ""}`, http.StatusBadRequest},
+		{"whitespace code", `{"language": "python", "code": " \n\t"}`, http.StatusBadRequest},
+		{"unknown language", `{"language": "cobol", "code": "x"}`, http.StatusBadRequest},
+		{"valid", `{"language": "python", "code": "print(1)"}`, http.StatusOK},
+	})
+}
+
+func TestCreateRejectsOversizedSource(t *testing.T) {
+	router, _ := newTestServer(t, Config{MaxSourceBytes: 64})
+	checkCreate(t, router, []struct {
+		name string
+		body string
+		want int
+	}{
+		{"at limit", `{"language": "python", "code": "` + strings.Repeat("x", 64) + `"}`, http.StatusOK},
+		{"oversized", `{"language": "python", "code": "` + strings.Repeat("x", 65) + `"}`, http.StatusBadRequest},
+	})
+}
+
+func TestCreateValidatesEntrypoint(t *testing.T) {
+	router, _ := newTestServer(t, Config{ValidateEntrypoint: true})
+	checkCreate(t, router, []struct {
+		name string
+		body string
+		want int
+	}{
+		{"entrypoint in additional files", `{"language": "python", "code": "", "additional_files": {"main.py": "cHJpbnQoMSk="}}`, http.StatusOK},
+		{"empty entrypoint in additional files", `{"language": "python", "code": "", "additional_files": {"main.py": "IA=="}}`, http.StatusBadRequest},
+		{"entrypoint missing", `{"language": "python", "code": "", "additional_files": {"helper.py": "eCA9IDE="}}`, http.StatusBadRequest},
+	})
+}
+
+func TestCreateValidatesWallTimeLimit(t *testing.T) {
+	router, _ := newTestServer(t, Config{})
+	checkCreate(t, router, []struct {
+		name string
+		body string
+		want int
+	}{
+		{"positive", `{"language": "python", "code": "print(1)", "wall_time_limit": 2.5}`, http.StatusOK},
+		{"zero", `{"language": "python", "code": "print(1)", "wall_time_limit": 0}`, http.StatusBadRequest},
+		{"negative", `{"language": "python", "code": "print(1)", "wall_time_limit": -1}`, http.StatusBadRequest},
+		{"over maximum", `{"language": "python", "code": "print(1)", "wall_time_limit": 1e9}`, http.StatusBadRequest},
+		{"overflowing", `{"language": "python", "code": "print(1)", "wall_time_limit": 1e999}`, http.StatusBadRequest},
+	})
+}
+
+func TestCreateDisabledFreeQueueRoutesToMain(t *testing.T) {
+	router, rc := newTestServer(t, Config{DisableFreeQueue: true})
+	rec := do(router, http.MethodPost, "/create", `{"language": "python", "code": "print(1)", "free": true}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", rec.Code, rec.Body)
+	}
+	ctx := context.Background()
+	if n, _ := rc.QueueLength(ctx, redis.QueueMain); n != 1 {
+		t.Errorf("main queue length = %d, want 1", n)
+	}
+	if n, _ := rc.QueueLength(ctx, redis.QueueFree); n != 0 {
+		t.Errorf("free queue length = %d, want 0", n)
+	}
+}
+
+func pythonJudge0ID(t *testing.T) int {
+	t.Helper()
+	ids := utils.Judge0LanguageIDsFor("python")
+	if len(ids) == 0 {
+		t.Fatal("python has no Judge0 language id")
+	}
+	return ids[0]
+}
+
+func TestSubmitBatchFallbackLanguage(t *testing.T) {
+	body := `{"submissions": [{"source_code": "print(1)", "language_id": 99999}]}`
+
+	router, _ := newTestServer(t, Config{})
+	if rec := do(router, http.MethodPost, "/submissions/batch", body, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("without fallback: status = %d, want 400", rec.Code)
+	}
+
+	router, rc := newTestServer(t, Config{Judge0FallbackLanguage: "python"})
+	rec := do(router, http.MethodPost, "/submissions/batch", body, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("with fallback: status = %d (%s)", rec.Code, rec.Body)
+	}
+	resp := decode[[]models.Judge0SubmissionResponse](t, rec)
+	id, _ := strconv.ParseUint(resp[0].Token, 10, 64)
+	job, err := rc.GetJob(context.Background(), id)
+	if err != nil || job == nil || job.Language.Name != "python" {
+		t.Errorf("job = %+v, %v; want a python job", job, err)
+	}
+}
+
+func TestSubmitBatchComparisonMode(t *testing.T) {
+	router, rc := newTestServer(t, Config{})
+	id := strconv.Itoa(pythonJudge0ID(t))
+	body := `{"submissions": [
+		{"source_code": "print(1)", "language_id": ` + id + `, "trim_whitespace": false},
+		{"source_code": "print(1)", "language_id": ` + id + `, "trim_whitespace": true},
+		{"source_code": "print(1)", "language_id": ` + id + `, "trim_whitespace": false, "comparison_mode": "token"}
+	]}`
+	rec := do(router, http.MethodPost, "/submissions/batch", body, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d (%s)", rec.Code, rec.Body)
+	}
+	want := []string{models.ComparisonExact, models.ComparisonTrailingTrim, models.ComparisonToken}
+	for i, resp := range decode[[]models.Judge0SubmissionResponse](t, rec) {
+		id, _ := strconv.ParseUint(resp.Token, 10, 64)
+		job, err := rc.GetJob(context.Background(), id)
+		if err != nil || job == nil {
+			t.Fatalf("job %s: %v", resp.Token, err)
+		}
+		if job.Settings.ComparisonMode != want[i] {
+			t.Errorf("submission %d: comparison mode = %q, want %q", i, job.Settings.ComparisonMode, want[i])
+		}
+	}
+
+	bad := `{"submissions": [{"source_code": "x", "language_id": ` + id + `, "comparison_mode": "fuzzy"}]}`
+	if rec := do(router, http.MethodPost, "/submissions/batch", bad, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown comparison mode: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestSubmitBatchPartialCapacity(t *testing.T) {
+	router, rc := newTestServer(t, Config{QueueLengthLimit: 2})
+	id := strconv.Itoa(pythonJudge0ID(t))
+	sub := `{"source_code": "print(1)", "language_id": ` + id + `}`
+	body := `{"submissions": [` + sub + `,` + sub + `,` + sub + `]}`
+
+	rec := do(router, http.MethodPost, "/submissions/batch", body, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d (%s)", rec.Code, rec.Body)
+	}
+	resp := decode[[]models.Judge0SubmissionResponse](t, rec)
+	if len(resp) != 3 || resp[0].Token == "" || resp[1].Token == "" || resp[2].Error != "queue limit reached" {
+		t.Errorf("responses = %+v, want two tokens and one queue limit error", resp)
+	}
+	if n, _ := rc.QueueLength(context.Background(), redis.QueueMain); n != 2 {
+		t.Errorf("queue length = %d, want 2", n)
+	}
+
+	if rec := do(router, http.MethodPost, "/submissions/batch", body, nil); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("full queue: status = %d, want 429", rec.Code)
+	}
+}
+
+func TestGetBatchSummary(t *testing.T) {
+	router, rc := newTestServer(t, Config{})
+	ctx := context.Background()
+	jobs := []models.Job{
+		{ID: 1, Status: models.JobStatus{Kind: models.StatusAccepted}, Output: models.JobOutput{Time: 0.5, Memory: 1000}},
+		{ID: 2, Status: models.JobStatus{Kind: models.StatusWrongAnswer}, Output: models.JobOutput{Time: 0.25, Memory: 3000}},
+		{ID: 3, Status: models.JobStatus{Kind: models.StatusQueued}},
+	}
+	for i := range jobs {
+		if err := rc.StoreJob(ctx, &jobs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := do(router, http.MethodGet, "/submissions/batch/summary?tokens=1,2,3,4", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", rec.Code, rec.Body)
+	}
+	summary := decode[models.BatchSummary](t, rec)
+	if summary.Total != 4 || summary.Finished != 2 || summary.Pending != 1 || summary.Missing != 1 || summary.Accepted != 1 {
+		t.Errorf("counts = %+v", summary)
+	}
+	if summary.Done {
+		t.Error("summary is done with a queued job")
+	}
+	if summary.TotalTime != 0.75 || summary.MaxMemory != 3000 {
+		t.Errorf("total time %v, max memory %d; want 0.75, 3000", summary.TotalTime, summary.MaxMemory)
+	}
+	if summary.FirstFailure == nil || summary.FirstFailure.Token != "2" {
+		t.Errorf("first failure = %+v, want token 2", summary.FirstFailure)
+	}
+
+	// Finished jobs next to a missing token: nothing is pending, but the
+	// missing token never resolves.
+	rec = do(router, http.MethodGet, "/submissions/batch/summary?tokens=1,2,4", "", nil)
+	if summary := decode[models.BatchSummary](t, rec); summary.Done || summary.Pending != 0 || summary.Missing != 1 {
+		t.Errorf("with a missing token: %+v, want not done", summary)
+	}
+	rec = do(router, http.MethodGet, "/submissions/batch/summary?tokens=1,2", "", nil)
+	if summary := decode[models.BatchSummary](t, rec); !summary.Done {
+		t.Errorf("all finished: %+v, want done", summary)
+	}
+}
+
+func TestGetBatchMissingToken(t *testing.T) {
+	router, rc := newTestServer(t, Config{})
+	job := models.Job{ID: 1, Status: models.JobStatus{Kind: models.StatusAccepted}}
+	if err := rc.StoreJob(context.Background(), &job); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/submissions/batch?tokens=1,2", "/submissions/batch?tokens=1,2&fields=token,status"} {
+		rec := do(router, http.MethodGet, path, "", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d (%s)", path, rec.Code, rec.Body)
+		}
+		batch := decode[models.Judge0BatchResponse](t, rec)
+		if len(batch.Submissions) != 2 {
+			t.Fatalf("%s: %d submissions, want 2", path, len(batch.Submissions))
+		}
+		if known := batch.Submissions[0]; known.Token != "1" || known.Status.ID != job.Status.ID() {
+			t.Errorf("%s: known token = %+v", path, known)
+		}
+		if missing := batch.Submissions[1]; missing.Token != "2" || missing.Status.ID != 13 {
+			t.Errorf("%s: unknown token = %+v, want status 13", path, missing)
+		}
+	}
+}
+
+func TestBase64EncodedOutput(t *testing.T) {
+	router, rc := newTestServer(t, Config{})
+	job := models.Job{ID: 1, Status: models.JobStatus{Kind: models.StatusAccepted}, Output: models.JobOutput{Stdout: "hi\n"}}
+	if err := rc.StoreJob(context.Background(), &job); err != nil {
+		t.Fatal(err)
+	}
+	const want = "aGkK"
+
+	rec := do(router, http.MethodGet, "/submissions/1?base64_encoded=true", "", nil)
+	single := decode[models.Judge0SubmissionDetails](t, rec)
+	if single.Stdout == nil || *single.Stdout != want {
+		t.Errorf("single stdout = %v, want %q", single.Stdout, want)
+	}
+
+	rec = do(router, http.MethodGet, "/submissions/batch?tokens=1&base64_encoded=true", "", nil)
+	batch := decode[models.Judge0BatchResponse](t, rec)
+	if len(batch.Submissions) != 1 || batch.Submissions[0].Stdout == nil || *batch.Submissions[0].Stdout != want {
+		t.Errorf("batch = %s, want stdout %q", rec.Body, want)
+	}
+
+	rec = do(router, http.MethodGet, "/submissions/batch?tokens=1", "", nil)
+	batch = decode[models.Judge0BatchResponse](t, rec)
+	if len(batch.Submissions) != 1 || batch.Submissions[0].Stdout == nil || *batch.Submissions[0].Stdout != "hi\n" {
+		t.Errorf("batch = %s, want plain stdout", rec.Body)
+	}
+}
+
+func TestRateLimitIgnoresUnvalidatedKeys(t *testing.T) {
+	router, _ := newTestServer(t, Config{RateLimitPerMinute: 1})
+	body := `{"language": "python", "code": "print(1)"}`
+
+	// Without configured API keys a random X-Auth-Token is not validated, so
+	// it must not buy the client a fresh bucket.
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		header := http.Header{apiKeyHeader: {"random-" + strconv.Itoa(i)}}
+		if rec := do(router, http.MethodPost, "/create", body, header); rec.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i, rec.Code, want)
+		}
+	}
+}
+
+func TestRateLimitPerValidatedKey(t *testing.T) {
+	router, _ := newTestServer(t, Config{RateLimitPerMinute: 1, APIKeys: []string{"alpha", "beta"}})
+	body := `{"language": "python", "code": "print(1)"}`
+
+	for _, tt := range []struct {
+		key  string
+		want int
+	}{
+		{"alpha", http.StatusOK},
+		{"alpha", http.StatusTooManyRequests},
+		{"beta", http.StatusOK},
+		{"gamma", http.StatusUnauthorized},
+	} {
+		if rec := do(router, http.MethodPost, "/create", body, http.Header{apiKeyHeader: {tt.key}}); rec.Code != tt.want {
+			t.Errorf("key %s: status = %d, want %d", tt.key, rec.Code, tt.want)
+		}
+	}
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCompressedRequestBodies(t *testing.T) {
+	router, _ := newTestServer(t, Config{MaxBodyBytes: 1 << 10})
+	post := func(body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/create", bytes.NewReader(gzipBytes(t, body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post([]byte(`{"language": "python", "code": "print(1)"}`)); rec.Code != http.StatusOK {
+		t.Errorf("gzip body: status = %d (%s)", rec.Code, rec.Body)
+	}
+
+	// A few kilobytes on the wire that expand well past the limit.
+	bomb := append([]byte(`{"language": "python", "code": "`), bytes.Repeat([]byte(" "), 1<<20)...)
+	if rec := post(bomb); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("gzip bomb: status = %d, want 413", rec.Code)
+	}
+}
+
+func TestMetricsNotDoubleCompressed(t *testing.T) {
+	router, _ := newTestServer(t, Config{})
+	rec := do(router, http.MethodGet, "/metrics", "", http.Header{"Accept-Encoding": {"gzip"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	body := io.Reader(rec.Body)
+	if rec.Header().Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = zr
+	}
+	text, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), "# HELP") {
+		t.Errorf("metrics body is not plain exposition text after one decode: %q", text[:min(len(text), 64)])
+	}
+}
+
+func TestAdminQueueEndpoints(t *testing.T) {
+	router, rc := newTestServer(t, Config{AdminToken: "secret"})
+	ctx := context.Background()
+	for id := uint64(1); id <= 3; id++ {
+		job := models.Job{ID: id, Status: models.JobStatus{Kind: models.StatusQueued}}
+		if err := rc.Enqueue(ctx, &job, redis.QueueMain); err != nil {
+			t.Fatal(err)
+		}
+	}
+	admin := http.Header{adminTokenHeader: {"secret"}}
+
+	if rec := do(router, http.MethodGet, "/admin/queue", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want 401", rec.Code)
+	}
+
+	rec := do(router, http.MethodGet, "/admin/queue?limit=2", "", admin)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("inspect: status = %d (%s)", rec.Code, rec.Body)
+	}
+	snapshot := decode[struct {
+		Queues []QueueSnapshot `json:"queues"`
+	}](t, rec)
+	for _, q := range snapshot.Queues {
+		if q.Queue != string(redis.QueueMain) {
+			continue
+		}
+		if q.Length != 3 || len(q.Jobs) != 2 || q.Jobs[0].Token != "1" || q.Jobs[0].Status == nil {
+			t.Errorf("main queue snapshot = %+v", q)
+		}
+	}
+	if rec := do(router, http.MethodGet, "/admin/queue?limit=0", "", admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: status = %d, want 400", rec.Code)
+	}
+
+	if rec := do(router, http.MethodDelete, "/admin/queue?queue=bogus", "", admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown queue: status = %d, want 400", rec.Code)
+	}
+	rec = do(router, http.MethodDelete, "/admin/queue?queue=main", "", admin)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("purge: status = %d (%s)", rec.Code, rec.Body)
+	}
+	purged := decode[struct {
+		Purged int      `json:"purged"`
+		Tokens []string `json:"tokens"`
+	}](t, rec)
+	if purged.Purged != 3 || !slices.Equal(purged.Tokens, []string{"1", "2", "3"}) {
+		t.Errorf("purge response = %+v, want tokens 1, 2 and 3", purged)
+	}
+	rec = do(router, http.MethodGet, "/check/1", "", nil)
+	if got := decode[models.CheckResponse](t, rec); got.Status.Description != "Cancelled" {
+		t.Errorf("purged job status = %+v, want Cancelled", got.Status)
+	}
+	if n, _ := rc.QueueLength(ctx, redis.QueueMain); n != 0 {
+		t.Errorf("queue length after purge = %d, want 0", n)
+	}
+}
+
+func TestAdminDisabledWithoutToken(t *testing.T) {
+	router, _ := newTestServer(t, Config{})
+	if rec := do(router, http.MethodGet, "/admin/queue", "", http.Header{adminTokenHeader: {""}}); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// idempotencyKeyHeader lets clients retry /create without creating a
+	// second job.
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayHeader marks responses that return an existing job.
+	idempotentReplayHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLen   = 255
+)
+
+// idempotencyKey returns the Redis key for the request's Idempotency-Key,
+// scoped to the client so different clients cannot collide. It returns ""
+// when the header is absent.
+func idempotencyKey(c *gin.Context) (string, error) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		return "", nil
+	}
+	if len(key) > maxIdempotencyKeyLen {
+		return "", errors.New("idempotency key is too long")
+	}
+	sum := sha256.Sum256([]byte(key))
+	return rateLimitKey(c) + ":" + hex.EncodeToString(sum[:16]), nil
+}
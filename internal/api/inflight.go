@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// inFlightKey identifies the client whose queued and running jobs are
+// counted: its validated API key or, without one, its IP.
+func inFlightKey(c *gin.Context) string {
+	return rateLimitKey(c)
+}
+
+// acquireInFlight reserves n in-flight slots for key and returns how many it
+// holds; jobs carrying key release one slot each when they finish. When the
+// key is over its limit it writes a 429 and returns false. Redis errors let
+// the request through without holding slots, like the rate limiter.
+func (h *Handler) acquireInFlight(c *gin.Context, key string, n int) (int, bool) {
+	if h.maxInFlightPerKey <= 0 || key == "" {
+		return 0, true
+	}
+	admitted, err := h.redis.AcquireInFlight(c.Request.Context(), key, n, h.maxInFlightPerKey)
+	if err != nil {
+		logrus.WithError(err).Warn("in-flight limiter unavailable, allowing request")
+		return 0, true
+	}
+	if !admitted {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many jobs in flight"})
+		return 0, false
+	}
+	return n, true
+}
+
+// releaseInFlight returns n slots reserved for jobs that were not enqueued.
+func (h *Handler) releaseInFlight(c *gin.Context, key string, n int) {
+	if n > 0 {
+		_ = h.redis.ReleaseInFlight(c.Request.Context(), key, n)
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// apiKeyHeader carries the client's API key.
+const apiKeyHeader = "X-Auth-Token"
+
+// rateLimit allows each client rateLimitPerMinute submissions per minute,
+// keyed by validated API key or, without one, by client IP. Redis errors let the
+// request through rather than failing submissions.
+func (h *Handler) rateLimit(c *gin.Context) {
+	if h.rateLimitPerMinute <= 0 {
+		c.Next()
+		return
+	}
+	allowed, retryAfter, err := h.redis.TakeToken(c.Request.Context(), rateLimitKey(c), h.rateLimitPerMinute)
+	if err != nil {
+		logrus.WithError(err).Warn("rate limiter unavailable, allowing request")
+		c.Next()
+		return
+	}
+	if !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+		return
+	}
+	c.Next()
+}
+
+// rateLimitKey identifies the client. Only keys requireAPIKey accepted are
+// used, so an unchecked X-Auth-Token cannot mint fresh buckets; they are
+// hashed so they are never stored in Redis in the clear.
+func rateLimitKey(c *gin.Context) string {
+	if key := c.GetString(apiKeyContextKey); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+	return "ip:" + c.ClientIP()
+}
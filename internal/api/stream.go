@@ -0,0 +1,71 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flash-go/internal/core"
+
+	"github.com/gin-gonic/gin"
+)
+
+const streamPollInterval = 500 * time.Millisecond
+
+// StreamSubmission handles GET /submissions/:token/stream
+// Sends the job's stdout as server-sent "stdout" events while it runs, then
+// a final "result" event with the check response once it is terminal.
+func (h *Handler) StreamSubmission(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("token"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	job, err := h.redis.GetJob(ctx, jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "submission not found"})
+		return
+	}
+
+	ticker := time.NewTicker(streamPollInterval)
+	defer ticker.Stop()
+	sent := 0
+	first := true
+	c.Stream(func(w io.Writer) bool {
+		if !first {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-ticker.C:
+			}
+			if job, err = h.redis.GetJob(ctx, jobID); err != nil || job == nil {
+				c.SSEvent("error", gin.H{"error": "failed to fetch job"})
+				return false
+			}
+		}
+		first = false
+
+		if job.Status.IsTerminal() {
+			c.SSEvent("result", core.NewCheckResponse(job, h.timestampUnit))
+			return false
+		}
+
+		stdout, err := h.redis.GetPartialOutput(ctx, jobID)
+		if err != nil {
+			c.SSEvent("error", gin.H{"error": "failed to fetch output"})
+			return false
+		}
+		if len(stdout) > sent {
+			c.SSEvent("stdout", stdout[sent:])
+			sent = len(stdout)
+		}
+		return true
+	})
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"flash-go/internal/core"
+	"flash-go/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+// GetSubmission handles GET /submissions/:token?base64_encoded=false&fields=...
+// Returns the Judge0 representation of a single submission. When fields is
+// set, only the listed keys are included; "*" includes all of them.
+func (h *Handler) GetSubmission(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("token"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token format"})
+		return
+	}
+
+	job, err := h.redis.GetJob(c.Request.Context(), jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "submission not found"})
+		return
+	}
+
+	details := h.judge0Details(job, c.Query("base64_encoded") == "true")
+
+	fields := parseFields(c.Query("fields"))
+	if fields == nil {
+		c.JSON(http.StatusOK, details)
+		return
+	}
+	filtered, err := selectFields(details, fields)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode submission"})
+		return
+	}
+	c.JSON(http.StatusOK, filtered)
+}
+
+// missingDetails describes a token with no stored job, either never created
+// or expired. Queued jobs are always stored, so they never end up here. The
+// message is base64-encoded when base64Encoded is set.
+func missingDetails(jobID uint64, base64Encoded bool) *models.Judge0SubmissionDetails {
+	status := models.JobStatus{Kind: models.StatusInternalError}
+	message := "submission not found"
+	details := &models.Judge0SubmissionDetails{
+		Token: strconv.FormatUint(jobID, 10),
+		Status: models.Judge0Status{
+			ID:          status.ID(),
+			Description: status.Description(),
+		},
+		Message: &message,
+	}
+	if base64Encoded {
+		encodeDetails(details)
+	}
+	return details
+}
+
+// judge0Details maps a job to its Judge0 submission representation, with the
+// text outputs base64-encoded when base64Encoded is set.
+func (h *Handler) judge0Details(job *models.Job, base64Encoded bool) *models.Judge0SubmissionDetails {
+	details := models.Judge0SubmissionDetails{
+		Token: strconv.FormatUint(job.ID, 10),
+		Status: models.Judge0Status{
+			ID:          job.Status.ID(),
+			Description: job.Status.Description(),
+		},
+		CreatedAt:  h.timestampUnit.Scale(job.CreatedAt),
+		StartedAt:  h.timestampUnit.Scale(job.StartedAt),
+		FinishedAt: h.timestampUnit.Scale(job.FinishedAt),
+		Truncated:  job.Output.Truncated,
+		IsCompiled: job.Language.IsCompiled,
+		SourceHash: job.SourceHash,
+		ExitCode:   core.ExitCode(job),
+		Signal:     job.Status.SignalName(),
+	}
+	if job.Status.Signal > 0 {
+		signal := job.Status.Signal
+		details.ExitSignal = &signal
+	}
+
+	if job.Output.Stdout != "" {
+		details.Stdout = &job.Output.Stdout
+	}
+	if job.Output.Stderr != "" {
+		details.Stderr = &job.Output.Stderr
+	}
+	if job.Output.CompileOutput != "" {
+		details.CompileOutput = &job.Output.CompileOutput
+	}
+	if job.Output.Message != "" {
+		details.Message = &job.Output.Message
+	} else if job.Status.Kind == models.StatusCompilationError && job.Output.CompileOutput != "" {
+		message := job.Output.CompileOutput
+		details.Message = &message
+	}
+	if job.Output.TimeAvailable {
+		timeStr := strconv.FormatFloat(job.Output.Time, 'f', -1, 64)
+		details.Time = &timeStr
+	}
+	if job.Output.Memory > 0 {
+		memory := int(job.Output.Memory)
+		details.Memory = &memory
+	}
+	if base64Encoded {
+		encodeDetails(&details)
+	}
+	return &details
+}
+
+// encodeDetails base64-encodes the text outputs of details in place.
+func encodeDetails(details *models.Judge0SubmissionDetails) {
+	for _, field := range []**string{&details.Stdout, &details.Stderr, &details.CompileOutput, &details.Message} {
+		if *field == nil {
+			continue
+		}
+		encoded := base64.StdEncoding.EncodeToString([]byte(**field))
+		*field = &encoded
+	}
+}
+
+// parseFields splits a comma-separated fields query value. It returns nil
+// when every field should be included (empty or "*").
+func parseFields(raw string) []string {
+	if raw == "" || raw == "*" {
+		return nil
+	}
+	var fields []string
+	for _, field := range strings.Split(raw, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// selectFields returns the JSON object for details restricted to fields.
+// Unknown keys are ignored.
+func selectFields(details *models.Judge0SubmissionDetails, fields []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
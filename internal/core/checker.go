@@ -0,0 +1,27 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"flash-go/internal/models"
+)
+
+// NewChecker resolves a checker request into the checker stored on a job,
+// using the checker language's default limits.
+func NewChecker(req models.CheckerRequest) (*models.Checker, error) {
+	lang, ok := LanguageFor(req.Language, "")
+	if !ok {
+		return nil, fmt.Errorf("unsupported checker language %q", req.Language)
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		return nil, errors.New("checker code is empty")
+	}
+	return &models.Checker{
+		SourceCode:  req.Code,
+		Language:    lang,
+		Settings:    SettingsFor(lang),
+		Interactive: req.Interactive,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxEnvVars is the largest number of environment variables a submission may set.
+const MaxEnvVars = 32
+
+// maxEnvValueBytes bounds the length of a single environment variable value.
+const maxEnvValueBytes = 1024
+
+// reservedEnv are variables the sandbox sets itself.
+var reservedEnv = map[string]bool{
+	"PATH": true,
+	"HOME": true,
+}
+
+// ValidateEnv checks that submission environment variables use plain names
+// and values from a safe character set and do not override sandbox variables.
+func ValidateEnv(env map[string]string) error {
+	if len(env) > MaxEnvVars {
+		return fmt.Errorf("too many env variables (max %d)", MaxEnvVars)
+	}
+	for key, value := range env {
+		if !validEnvKey(key) {
+			return fmt.Errorf("invalid env variable name %q", key)
+		}
+		if reservedEnv[strings.ToUpper(key)] {
+			return fmt.Errorf("env variable %s is reserved", key)
+		}
+		if len(value) > maxEnvValueBytes || !validEnvValue(value) {
+			return fmt.Errorf("invalid value for env variable %s", key)
+		}
+	}
+	return nil
+}
+
+// validEnvKey reports whether key is a POSIX-style variable name.
+func validEnvKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validEnvValue reports whether value only contains characters that are safe
+// to pass to isolate.
+func validEnvValue(value string) bool {
+	for _, r := range value {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case strings.ContainsRune(" ._-:/,=+@%", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -2,7 +2,9 @@ package core
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"time"
 
 	"flash-go/internal/models"
@@ -34,6 +36,13 @@ func NewJobID() uint64 {
 	return uint64(time.Now().UnixNano())
 }
 
+// SourceHash returns a short content hash of source code: the first 12 hex
+// characters of its SHA-256, like a git short hash.
+func SourceHash(sourceCode string) string {
+	sum := sha256.Sum256([]byte(sourceCode))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
 // RuntimeErrorStatus creates a runtime error status.
 func RuntimeErrorStatus(code string) models.JobStatus {
 	return models.JobStatus{
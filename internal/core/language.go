@@ -1,59 +1,332 @@
 package core
 
-import "flash-go/internal/models"
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
 
-// LanguageFor returns the language configuration for a given name.
-func LanguageFor(name string) (models.Language, bool) {
-	switch name {
-	case "python":
-		return models.Language{
+	"flash-go/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// languages holds the supported language configurations keyed by name.
+var languages = map[string]models.Language{
+	"python": {
+		Name:       "python",
+		SourceFile: "main.py",
+		CompileCmd: "",
+		RunCmd:     "/usr/bin/python3 main.py",
+		IsCompiled: false,
+	},
+	"cpp": {
+		Name:       "cpp",
+		SourceFile: "main.cpp",
+		CompileCmd: "/usr/bin/g++ -O0 -Wall -Wextra -g -w -fsanitize=undefined -fno-omit-frame-pointer main.cpp -o {artifact}",
+		RunCmd:     "./{artifact}",
+		IsCompiled: true,
+		Artifact:   "a.out",
+	},
+	"javascript": {
+		Name:       "javascript",
+		SourceFile: "main.js",
+		CompileCmd: "",
+		RunCmd:     "/usr/bin/node main.js",
+		IsCompiled: false,
+	},
+	"ruby": {
+		Name:       "ruby",
+		SourceFile: "main.rb",
+		CompileCmd: "",
+		RunCmd:     "/usr/bin/ruby main.rb",
+		IsCompiled: false,
+	},
+	"typescript": {
+		Name:       "typescript",
+		SourceFile: "main.ts",
+		CompileCmd: "/usr/bin/tsc main.ts",
+		RunCmd:     "/usr/bin/node main.js",
+		IsCompiled: true,
+	},
+	"java": {
+		Name:            "java",
+		SourceFile:      "Main.java",
+		CompileCmd:      "/usr/bin/javac Main.java",
+		RunCmd:          "/usr/bin/java Main",
+		IsCompiled:      true,
+		DefaultSettings: javaSettings(),
+	},
+	"kotlin": {
+		Name:            "kotlin",
+		SourceFile:      "Main.kt",
+		CompileCmd:      "/usr/bin/kotlinc Main.kt -include-runtime -d main.jar",
+		RunCmd:          "/usr/bin/java -jar main.jar",
+		IsCompiled:      true,
+		DefaultSettings: kotlinSettings(),
+	},
+	"csharp": {
+		Name:       "csharp",
+		SourceFile: "main.cs",
+		CompileCmd: "/usr/bin/mcs -optimize+ -out:{artifact} main.cs",
+		RunCmd:     "/usr/bin/mono {artifact}",
+		IsCompiled: true,
+		Artifact:   "main.exe",
+	},
+	// R prints startup notices and warnings to stderr, which is captured
+	// separately and never compared against the expected output.
+	"r": {
+		Name:       "r",
+		SourceFile: "main.R",
+		CompileCmd: "",
+		RunCmd:     "/usr/bin/Rscript main.R",
+		IsCompiled: false,
+	},
+	"go": {
+		Name:       "go",
+		SourceFile: "main.go",
+		CompileCmd: "GO111MODULE=off /usr/bin/go build -o {artifact} main.go",
+		RunCmd:     "./{artifact}",
+		IsCompiled: true,
+		Artifact:   "main",
+	},
+}
+
+// languageVersions holds alternative toolchains keyed by language name and
+// version. Requests without a version use the entry in languages.
+var languageVersions = map[string]map[string]models.Language{
+	"python": {
+		"3.11": {
 			Name:       "python",
+			Version:    "3.11",
 			SourceFile: "main.py",
 			CompileCmd: "",
-			RunCmd:     "/usr/bin/python3 main.py",
+			RunCmd:     "/usr/bin/python3.11 main.py",
 			IsCompiled: false,
-		}, true
-	case "cpp":
-		return models.Language{
-			Name:       "cpp",
-			SourceFile: "main.cpp",
-			CompileCmd: "/usr/bin/g++ -O0 -Wall -Wextra -g -w -fsanitize=undefined -fno-omit-frame-pointer main.cpp",
-			RunCmd:     "./a.out",
-			IsCompiled: true,
-		}, true
-	case "javascript":
-		return models.Language{
-			Name:       "javascript",
-			SourceFile: "main.js",
+		},
+		"3.12": {
+			Name:       "python",
+			Version:    "3.12",
+			SourceFile: "main.py",
 			CompileCmd: "",
-			RunCmd:     "/usr/bin/node main.js",
+			RunCmd:     "/usr/bin/python3.12 main.py",
 			IsCompiled: false,
-		}, true
-	case "java":
-		return models.Language{
-			Name:       "java",
-			SourceFile: "Main.java",
-			CompileCmd: "/usr/bin/javac Main.java",
-			RunCmd:     "/usr/bin/java Main",
-			IsCompiled: true,
-		}, true
-	case "csharp":
-		return models.Language{
-			Name:       "csharp",
-			SourceFile: "main.cs",
-			CompileCmd: "/usr/bin/mcs -optimize+ -out:main.exe main.cs",
-			RunCmd:     "/usr/bin/mono main.exe",
-			IsCompiled: true,
-		}, true
-	case "go":
-		return models.Language{
-			Name:       "go",
-			SourceFile: "main.go",
-			CompileCmd: "GO111MODULE=off /usr/bin/go build -o main main.go",
-			RunCmd:     "./main",
+		},
+	},
+	"cpp": {
+		"clang": {
+			Name:       "cpp",
+			Version:    "clang",
+			SourceFile: "main.cpp",
+			CompileCmd: "/usr/bin/clang++ -O0 -Wall -Wextra -g -w -fsanitize=undefined -fno-omit-frame-pointer main.cpp -o {artifact}",
+			RunCmd:     "./{artifact}",
 			IsCompiled: true,
-		}, true
+			Artifact:   "a.out",
+		},
+	},
+}
+
+// LanguageFor returns the language configuration for a given name and
+// version. An empty version selects the default; an unknown version falls
+// back to the default with a warning.
+func LanguageFor(name, version string) (models.Language, bool) {
+	lang, ok := languages[name]
+	if !ok || version == "" {
+		return lang, ok
+	}
+	if versioned, ok := languageVersions[name][version]; ok {
+		return versioned, true
+	}
+	logrus.WithFields(logrus.Fields{
+		"language": name,
+		"version":  version,
+	}).Warn("unknown language version, using default")
+	return lang, true
+}
+
+// LanguageVersions returns the non-default versions available for a language,
+// sorted.
+func LanguageVersions(name string) []string {
+	versions := make([]string, 0, len(languageVersions[name]))
+	for version := range languageVersions[name] {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Languages returns every supported language sorted by name.
+func Languages() []models.Language {
+	list := make([]models.Language, 0, len(languages))
+	for _, lang := range languages {
+		list = append(list, lang)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// WithSourceFile returns lang reconfigured to write the submission to name.
+// The name must be a plain filename (no path separators or "..") keeping the
+// extension of the default source file. Occurrences of the default filename in
+// the compile and run commands are replaced, and run command arguments named
+// after the file stem, such as the Java main class or the main.js TypeScript
+// compiles to, are renamed to the new stem.
+func WithSourceFile(lang models.Language, name string) (models.Language, error) {
+	if !validFilename(name) {
+		return models.Language{}, errors.New("invalid source file name")
+	}
+	ext := filepath.Ext(lang.SourceFile)
+	if filepath.Ext(name) != ext {
+		return models.Language{}, fmt.Errorf("source file must have extension %q", ext)
+	}
+
+	oldStem := strings.TrimSuffix(lang.SourceFile, ext)
+	newStem := strings.TrimSuffix(name, ext)
+	lang.CompileCmd = replaceToken(lang.CompileCmd, lang.SourceFile, name)
+	lang.RunCmd = replaceStem(lang.RunCmd, oldStem, newStem)
+	lang.SourceFile = name
+	return lang, nil
+}
+
+// MaxAdditionalFiles is the largest number of extra files a submission may upload.
+const MaxAdditionalFiles = 32
+
+// reservedFiles are box files the executor writes itself.
+var reservedFiles = map[string]bool{
+	"stdin":          true,
+	"stdout":         true,
+	"stderr":         true,
+	"metadata":       true,
+	"compile_output": true,
+}
+
+// ValidateAdditionalFiles checks that every extra file has a plain filename
+// that does not clash with the source file or the executor's own files.
+func ValidateAdditionalFiles(lang models.Language, files map[string]string) error {
+	if len(files) > MaxAdditionalFiles {
+		return fmt.Errorf("too many additional_files (max %d)", MaxAdditionalFiles)
+	}
+	for name := range files {
+		if !validFilename(name) {
+			return fmt.Errorf("invalid additional file name %q", name)
+		}
+		if name == lang.SourceFile || reservedFiles[name] {
+			return fmt.Errorf("additional file name %q is reserved", name)
+		}
+	}
+	return nil
+}
+
+// ValidateInputMode checks that mode is known and, in file mode, that the
+// input file does not clash with the source or an additional file.
+func ValidateInputMode(mode string, lang models.Language, files map[string]string) error {
+	switch mode {
+	case "", models.InputModeStdin:
+		return nil
+	case models.InputModeFile:
+		if _, ok := files[models.InputFile]; ok || lang.SourceFile == models.InputFile {
+			return fmt.Errorf("file name %q is reserved in input_mode file", models.InputFile)
+		}
+		return nil
 	default:
-		return models.Language{}, false
+		return fmt.Errorf("unknown input_mode %q", mode)
+	}
+}
+
+// ValidateRunOnly checks that lang runs a compiled artifact that binary can
+// stand in for, and that binary fits in maxFileSizeKB.
+func ValidateRunOnly(lang models.Language, binary []byte, maxFileSizeKB uint64) error {
+	if !lang.IsCompiled || lang.Artifact == "" {
+		return fmt.Errorf("language %s does not support run_only", lang.Name)
+	}
+	if len(binary) == 0 {
+		return errors.New("binary is empty")
+	}
+	if uint64(len(binary)) > maxFileSizeKB*1024 {
+		return fmt.Errorf("binary exceeds max file size of %d KB", maxFileSizeKB)
+	}
+	return nil
+}
+
+// WithArtifact returns lang with its compiled artifact renamed to name.
+// The language's commands must refer to the artifact via the placeholder.
+func WithArtifact(lang models.Language, name string) (models.Language, error) {
+	if lang.Artifact == "" || !strings.Contains(lang.RunCmd, models.ArtifactPlaceholder) {
+		return models.Language{}, fmt.Errorf("language %s does not support artifact naming", lang.Name)
+	}
+	if !validFilename(name) || name == lang.SourceFile || reservedFiles[name] {
+		return models.Language{}, errors.New("invalid artifact name")
+	}
+	lang.Artifact = name
+	return lang, nil
+}
+
+// WithStrictMode returns lang compiling with warnings treated as errors.
+// Only C++ supports it; the default build silences warnings with -w, which
+// strict mode drops so -Werror has something to act on.
+func WithStrictMode(lang models.Language) (models.Language, error) {
+	if lang.Name != "cpp" {
+		return models.Language{}, fmt.Errorf("language %s does not support strict_mode", lang.Name)
+	}
+	fields := strings.Fields(lang.CompileCmd)
+	parts := make([]string, 0, len(fields)+1)
+	for _, field := range fields {
+		switch field {
+		case "-w", "-Werror":
+			continue
+		case lang.SourceFile:
+			parts = append(parts, "-Werror")
+		}
+		parts = append(parts, field)
+	}
+	lang.CompileCmd = strings.Join(parts, " ")
+	return lang, nil
+}
+
+// validFilename reports whether name is a plain filename safe to create inside the box.
+func validFilename(name string) bool {
+	if name == "" || name == "." || strings.Contains(name, "..") {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// replaceStem renames whitespace-separated fields of cmd that are oldStem or
+// oldStem with an extension to newStem, keeping the extension.
+func replaceStem(cmd, oldStem, newStem string) string {
+	if cmd == "" || oldStem == "" {
+		return cmd
+	}
+	fields := strings.Fields(cmd)
+	for i, field := range fields {
+		if field == oldStem || strings.HasPrefix(field, oldStem+".") {
+			fields[i] = newStem + field[len(oldStem):]
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// replaceToken replaces whitespace-separated fields of cmd equal to old.
+func replaceToken(cmd, old, new string) string {
+	if cmd == "" || old == "" {
+		return cmd
+	}
+	fields := strings.Fields(cmd)
+	for i, field := range fields {
+		if field == old {
+			fields[i] = new
+		}
 	}
+	return strings.Join(fields, " ")
 }
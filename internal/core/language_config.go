@@ -0,0 +1,122 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"flash-go/internal/models"
+
+	"github.com/goccy/go-json"
+)
+
+// LoadLanguageConfig reads a JSON array of language definitions from path and
+// merges them into the supported languages, replacing built-ins with the same
+// name and version. Definitions with a version add an alternative toolchain
+// for a language that must also be defined. It must be called during startup,
+// before requests are served.
+func LoadLanguageConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var defs []languageDef
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	type key struct{ name, version string }
+	loaded := make(map[key]models.Language, len(defs))
+	for i, def := range defs {
+		lang := def.Language
+		if err := validateLanguage(lang); err != nil {
+			return fmt.Errorf("%s: language %d: %w", path, i, err)
+		}
+		if len(def.DefaultSettings) > 0 {
+			// Fields left out keep the built-in value instead of zeroing.
+			settings := SettingsFor(languages[lang.Name])
+			if err := json.Unmarshal(def.DefaultSettings, &settings); err != nil {
+				return fmt.Errorf("%s: language %d: default_settings: %w", path, i, err)
+			}
+			if err := validateSettings(settings); err != nil {
+				return fmt.Errorf("%s: language %d: %s: default_settings: %w", path, i, lang.Name, err)
+			}
+			lang.DefaultSettings = &settings
+		}
+		k := key{lang.Name, lang.Version}
+		if _, dup := loaded[k]; dup {
+			return fmt.Errorf("language %q version %q is defined more than once", lang.Name, lang.Version)
+		}
+		loaded[k] = lang
+	}
+	for k := range loaded {
+		if k.version == "" {
+			continue
+		}
+		if _, ok := loaded[key{k.name, ""}]; ok {
+			continue
+		}
+		if _, ok := languages[k.name]; !ok {
+			return fmt.Errorf("language %q version %q has no default definition", k.name, k.version)
+		}
+	}
+	for k, lang := range loaded {
+		if k.version == "" {
+			languages[k.name] = lang
+			continue
+		}
+		if languageVersions[k.name] == nil {
+			languageVersions[k.name] = make(map[string]models.Language)
+		}
+		languageVersions[k.name][k.version] = lang
+	}
+	return nil
+}
+
+// languageDef is a language definition as written in the config file.
+// DefaultSettings is kept raw so it can be decoded over the built-in defaults.
+type languageDef struct {
+	models.Language
+	DefaultSettings json.RawMessage `json:"default_settings,omitempty"`
+}
+
+// validateSettings rejects limits that would leave a job unable to run.
+func validateSettings(s models.ExecutionSettings) error {
+	for _, limit := range []struct {
+		name  string
+		value float64
+	}{
+		{"cpu_time_limit", s.CPUTimeLimit},
+		{"max_cpu_time_limit", s.MaxCPUTimeLimit},
+		{"wall_time_limit", s.WallTimeLimit},
+		{"max_wall_time_limit", s.MaxWallTimeLimit},
+		{"memory_limit", float64(s.MemoryLimit)},
+		{"max_memory_limit", float64(s.MaxMemoryLimit)},
+		{"stack_limit", float64(s.StackLimit)},
+		{"max_stack_limit", float64(s.MaxStackLimit)},
+		{"max_processes", float64(s.MaxProcesses)},
+	} {
+		if !(limit.value > 0) {
+			return fmt.Errorf("%s must be positive", limit.name)
+		}
+	}
+	return nil
+}
+
+// validateLanguage checks the fields a language needs to be executed.
+func validateLanguage(lang models.Language) error {
+	switch {
+	case lang.Name == "":
+		return fmt.Errorf("name is required")
+	case lang.SourceFile == "":
+		return fmt.Errorf("%s: source_file is required", lang.Name)
+	case !validFilename(lang.SourceFile) || reservedFiles[lang.SourceFile]:
+		return fmt.Errorf("%s: invalid source_file %q", lang.Name, lang.SourceFile)
+	case lang.RunCmd == "":
+		return fmt.Errorf("%s: run_cmd is required", lang.Name)
+	case lang.IsCompiled && lang.CompileCmd == "":
+		return fmt.Errorf("%s: compile_cmd is required for compiled languages", lang.Name)
+	case !lang.IsCompiled && lang.CompileCmd != "":
+		return fmt.Errorf("%s: compile_cmd is set but is_compiled is false", lang.Name)
+	}
+	return nil
+}
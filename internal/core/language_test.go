@@ -0,0 +1,221 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"flash-go/internal/models"
+	"flash-go/internal/utils"
+)
+
+func TestLanguageForRuby(t *testing.T) {
+	lang, ok := LanguageFor("ruby", "")
+	if !ok {
+		t.Fatal("ruby is not supported")
+	}
+	if lang.SourceFile != "main.rb" || lang.IsCompiled || lang.CompileCmd != "" {
+		t.Errorf("unexpected ruby definition %+v", lang)
+	}
+	for _, id := range utils.Judge0LanguageIDsFor("ruby") {
+		name, ok := utils.Judge0LanguageIDToName(id)
+		if !ok {
+			t.Fatalf("Judge0 id %d does not map back", id)
+		}
+		if _, ok := LanguageFor(name, ""); !ok {
+			t.Errorf("Judge0 id %d maps to unknown language %q", id, name)
+		}
+	}
+}
+
+func TestInterpretedLanguagesHaveNoCompileStep(t *testing.T) {
+	for _, lang := range Languages() {
+		if lang.IsCompiled != (lang.CompileCmd != "") {
+			t.Errorf("%s: IsCompiled = %v with compile command %q", lang.Name, lang.IsCompiled, lang.CompileCmd)
+		}
+	}
+	job := models.Job{Language: languages["python"]}
+	if resp := NewCheckResponse(&job, TimestampNanoseconds); resp.IsCompiled {
+		t.Error("python check response reports is_compiled")
+	}
+	job.Language = languages["cpp"]
+	if resp := NewCheckResponse(&job, TimestampNanoseconds); !resp.IsCompiled {
+		t.Error("cpp check response does not report is_compiled")
+	}
+}
+
+func TestLanguageForVersion(t *testing.T) {
+	lang, ok := LanguageFor("python", "3.12")
+	if !ok || lang.Version != "3.12" {
+		t.Errorf("LanguageFor(python, 3.12) = %+v, %v", lang, ok)
+	}
+	lang, ok = LanguageFor("python", "2.7")
+	if !ok || lang.Version != "" {
+		t.Errorf("unknown version did not fall back to default: %+v, %v", lang, ok)
+	}
+	if _, ok := LanguageFor("cobol", ""); ok {
+		t.Error("LanguageFor(cobol) found a language")
+	}
+}
+
+func TestWithArtifact(t *testing.T) {
+	lang, err := WithArtifact(languages["cpp"], "solution")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(lang.CompileCommand(), "-o solution") || lang.RunCommand() != "./solution" {
+		t.Errorf("commands not renamed: compile %q, run %q", lang.CompileCommand(), lang.RunCommand())
+	}
+	if _, err := WithArtifact(languages["python"], "solution"); err == nil {
+		t.Error("WithArtifact(python) = nil error")
+	}
+	for _, name := range []string{"../x", "main.cpp", "stdout", "a b"} {
+		if _, err := WithArtifact(languages["cpp"], name); err == nil {
+			t.Errorf("WithArtifact(cpp, %q) = nil error", name)
+		}
+	}
+}
+
+func TestWithSourceFile(t *testing.T) {
+	lang, err := WithSourceFile(languages["java"], "Solution.java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lang.CompileCmd != "/usr/bin/javac Solution.java" || lang.RunCmd != "/usr/bin/java Solution" {
+		t.Errorf("unexpected commands %q / %q", lang.CompileCmd, lang.RunCmd)
+	}
+	// TypeScript runs the JavaScript tsc emits next to the source.
+	lang, err = WithSourceFile(languages["typescript"], "solution.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lang.CompileCmd != "/usr/bin/tsc solution.ts" || lang.RunCmd != "/usr/bin/node solution.js" {
+		t.Errorf("unexpected typescript commands %q / %q", lang.CompileCmd, lang.RunCmd)
+	}
+	// Kotlin's jar name does not follow the source file.
+	lang, err = WithSourceFile(languages["kotlin"], "Solution.kt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lang.CompileCmd != "/usr/bin/kotlinc Solution.kt -include-runtime -d main.jar" || lang.RunCmd != "/usr/bin/java -jar main.jar" {
+		t.Errorf("unexpected kotlin commands %q / %q", lang.CompileCmd, lang.RunCmd)
+	}
+	for _, name := range []string{"Solution.kt", "../Main.java", "dir/Main.java"} {
+		if _, err := WithSourceFile(languages["java"], name); err == nil {
+			t.Errorf("WithSourceFile(java, %q) = nil error", name)
+		}
+	}
+}
+
+func TestValidateAdditionalFiles(t *testing.T) {
+	cpp := languages["cpp"]
+	if err := ValidateAdditionalFiles(cpp, map[string]string{"helper.h": "int f();"}); err != nil {
+		t.Errorf("valid header rejected: %v", err)
+	}
+	for _, name := range []string{"../escape.h", "dir/file.h", "..", "main.cpp", "stdout", ""} {
+		if err := ValidateAdditionalFiles(cpp, map[string]string{name: ""}); err == nil {
+			t.Errorf("ValidateAdditionalFiles(%q) = nil error", name)
+		}
+	}
+}
+
+func TestValidateEnv(t *testing.T) {
+	if err := ValidateEnv(map[string]string{"GREETING": "hello world", "_X1": "a=b"}); err != nil {
+		t.Errorf("valid env rejected: %v", err)
+	}
+	for _, env := range []map[string]string{
+		{"PATH": "/tmp"},
+		{"home": "/tmp"},
+		{"1X": "a"},
+		{"X-Y": "a"},
+		{"X": "$(id)"},
+		{"X": "a;b"},
+		{"X": strings.Repeat("a", maxEnvValueBytes+1)},
+	} {
+		if err := ValidateEnv(env); err == nil {
+			t.Errorf("ValidateEnv(%v) = nil error", env)
+		}
+	}
+}
+
+func TestWithStrictMode(t *testing.T) {
+	lang, err := WithStrictMode(languages["cpp"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(lang.CompileCmd)
+	if !containsField(fields, "-Werror") || containsField(fields, "-w") {
+		t.Errorf("strict compile command %q", lang.CompileCmd)
+	}
+	if _, err := WithStrictMode(languages["java"]); err == nil {
+		t.Error("WithStrictMode(java) = nil error")
+	}
+}
+
+func containsField(fields []string, want string) bool {
+	for _, f := range fields {
+		if f == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLoadLanguageConfig(t *testing.T) {
+	saved := languages["haskell"]
+	defer func() {
+		if saved.Name == "" {
+			delete(languages, "haskell")
+		} else {
+			languages["haskell"] = saved
+		}
+	}()
+
+	path := filepath.Join(t.TempDir(), "languages.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`[{"name": "haskell", "source_file": "Main.hs", "compile_cmd": "/usr/bin/ghc Main.hs", "run_cmd": "./Main", "is_compiled": true,
+		"default_settings": {"cpu_time_limit": 3}}]`)
+	if err := LoadLanguageConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	lang, ok := LanguageFor("haskell", "")
+	if !ok {
+		t.Fatal("haskell was not loaded")
+	}
+	settings := SettingsFor(lang)
+	defaults := DefaultExecutionSettings()
+	if settings.CPUTimeLimit != 3 {
+		t.Errorf("cpu_time_limit = %v, want 3", settings.CPUTimeLimit)
+	}
+	if settings.MemoryLimit != defaults.MemoryLimit || settings.MaxProcesses != defaults.MaxProcesses || settings.WallTimeLimit != defaults.WallTimeLimit {
+		t.Errorf("omitted limits were not kept from the defaults: %+v", settings)
+	}
+
+	for _, bad := range []string{
+		`[{"name": "haskell", "source_file": "Main.hs", "run_cmd": "./Main", "default_settings": {"memory_limit": 0}}]`,
+		`[{"name": "haskell", "source_file": "Main.hs", "run_cmd": "./Main", "default_settings": {"cpu_time_limit": -1}}]`,
+		`[{"name": "haskell", "source_file": "Main.hs", "run_cmd": ""}]`,
+		`[{"name": "haskell", "source_file": "../Main.hs", "run_cmd": "./Main"}]`,
+		`[{"name": "haskell", "source_file": "Main.hs", "run_cmd": "./Main", "is_compiled": true}]`,
+		`[{"name": "haskell", "source_file": "Main.hs", "run_cmd": "./Main"}, {"name": "haskell", "source_file": "Main.hs", "run_cmd": "./Main"}]`,
+		`[{"name": "cobol", "version": "85", "source_file": "main.cob", "run_cmd": "./main"}]`,
+	} {
+		write(bad)
+		err := LoadLanguageConfig(path)
+		if err == nil {
+			t.Errorf("LoadLanguageConfig(%s) = nil error", bad)
+		}
+	}
+
+	write(`[{"name": "haskell", "source_file": "Main.hs", "run_cmd": "./Main", "default_settings": {"max_processes": 0}}]`)
+	if err := LoadLanguageConfig(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("error %v does not name the config file", err)
+	}
+}
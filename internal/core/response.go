@@ -0,0 +1,74 @@
+package core
+
+import (
+	"time"
+
+	"flash-go/internal/models"
+)
+
+// NewCheckResponse builds the API representation of a job with timestamps in unit.
+// Time is nil when isolate did not report a measurement.
+func NewCheckResponse(job *models.Job, unit TimestampUnit) models.CheckResponse {
+	var execTime *float64
+	if job.Output.TimeAvailable {
+		t := job.Output.Time
+		execTime = &t
+	}
+	return models.CheckResponse{
+		CreatedAt:       unit.Scale(job.CreatedAt),
+		StartedAt:       unit.Scale(job.StartedAt),
+		FinishedAt:      unit.Scale(job.FinishedAt),
+		Stdout:          job.Output.Stdout,
+		Time:            execTime,
+		Memory:          job.Output.Memory,
+		CgMem:           job.Output.CgMem,
+		MaxRSS:          job.Output.MaxRSS,
+		Stderr:          job.Output.Stderr,
+		Token:           job.ID,
+		CompileOutput:   job.Output.CompileOutput,
+		Message:         job.Output.Message,
+		Truncated:       job.Output.Truncated,
+		StdoutTruncated: job.Output.StdoutTruncated,
+		StderrTruncated: job.Output.StderrTruncated,
+		IsCompiled:      job.Language.IsCompiled,
+		StressVerdicts:  job.Output.StressVerdicts,
+		StressDivergent: job.Output.StressDivergent,
+		Diff:            job.Output.Diff,
+		SourceHash:      job.SourceHash,
+		Attempts:        job.Attempts,
+		ExitCode:        ExitCode(job),
+		Signal:          job.Status.SignalName(),
+		QueueTimeMs:     elapsedMs(job.CreatedAt, job.StartedAt),
+		WallTimeMs:      elapsedMs(job.StartedAt, job.FinishedAt),
+		Status: models.CheckStatus{
+			ID:          job.Status.ID(),
+			Description: job.Status.Description(),
+		},
+	}
+}
+
+// ExitCode returns the program's exit code, or nil if the program has not
+// run to completion (still queued, processing, failed to compile, hit an
+// internal error or was submitted compile_only).
+func ExitCode(job *models.Job) *int {
+	if job.Settings.CompileOnly {
+		return nil
+	}
+	switch job.Status.Kind {
+	case models.StatusQueued, models.StatusProcessing, models.StatusCompilationError,
+		models.StatusCancelled, models.StatusInternalError:
+		return nil
+	}
+	code := job.Output.ExitCode
+	return &code
+}
+
+// elapsedMs returns the milliseconds between two nanosecond timestamps, or nil
+// if either has not been recorded yet.
+func elapsedMs(from, to int64) *int64 {
+	if from == 0 || to == 0 || to < from {
+		return nil
+	}
+	ms := (to - from) / int64(time.Millisecond)
+	return &ms
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"testing"
+
+	"flash-go/internal/models"
+)
+
+func TestTimestampUnitScale(t *testing.T) {
+	const ns = int64(1_700_000_000_123_456_789)
+	tests := []struct {
+		unit TimestampUnit
+		want int64
+	}{
+		{TimestampNanoseconds, ns},
+		{TimestampMilliseconds, 1_700_000_000_123},
+		{TimestampSeconds, 1_700_000_000},
+		{"", ns},
+	}
+	for _, tt := range tests {
+		if got := tt.unit.Scale(ns); got != tt.want {
+			t.Errorf("%q.Scale = %d, want %d", tt.unit, got, tt.want)
+		}
+		if got := tt.unit.Scale(0); got != 0 {
+			t.Errorf("%q.Scale(0) = %d, want 0", tt.unit, got)
+		}
+	}
+}
+
+func TestParseTimestampUnit(t *testing.T) {
+	for _, s := range []string{"ns", "ms", "s"} {
+		if _, err := ParseTimestampUnit(s); err != nil {
+			t.Errorf("ParseTimestampUnit(%q) = %v", s, err)
+		}
+	}
+	if _, err := ParseTimestampUnit("us"); err == nil {
+		t.Error("ParseTimestampUnit(us) = nil error")
+	}
+}
+
+func TestNewCheckResponseTimestamps(t *testing.T) {
+	job := models.Job{
+		CreatedAt:  2_000_000_000,
+		StartedAt:  2_500_000_000,
+		FinishedAt: 4_000_000_000,
+		Status:     models.JobStatus{Kind: models.StatusAccepted},
+	}
+	resp := NewCheckResponse(&job, TimestampMilliseconds)
+	if resp.CreatedAt != 2000 || resp.StartedAt != 2500 || resp.FinishedAt != 4000 {
+		t.Errorf("timestamps = %d/%d/%d, want 2000/2500/4000", resp.CreatedAt, resp.StartedAt, resp.FinishedAt)
+	}
+	if resp.QueueTimeMs == nil || *resp.QueueTimeMs != 500 || resp.WallTimeMs == nil || *resp.WallTimeMs != 1500 {
+		t.Errorf("durations = %v/%v, want 500/1500", resp.QueueTimeMs, resp.WallTimeMs)
+	}
+	if resp.Time != nil {
+		t.Errorf("time = %v for a job without a measurement, want nil", *resp.Time)
+	}
+
+	job.Output.Time, job.Output.TimeAvailable = 0.25, true
+	if resp := NewCheckResponse(&job, TimestampSeconds); resp.Time == nil || *resp.Time != 0.25 || resp.FinishedAt != 4 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		job  models.Job
+		want *int
+	}{
+		{"accepted", models.Job{Status: models.JobStatus{Kind: models.StatusAccepted}}, intPtr(0)},
+		{"runtime error", models.Job{Status: models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "NZEC"}, Output: models.JobOutput{ExitCode: 3}}, intPtr(3)},
+		{"queued", models.Job{Status: models.JobStatus{Kind: models.StatusQueued}}, nil},
+		{"compilation error", models.Job{Status: models.JobStatus{Kind: models.StatusCompilationError}}, nil},
+		{"internal error", models.Job{Status: models.JobStatus{Kind: models.StatusInternalError}}, nil},
+		{"compile only", models.Job{Status: models.JobStatus{Kind: models.StatusAccepted}, Settings: models.ExecutionSettings{CompileOnly: true}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExitCode(&tt.job)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("ExitCode = %d, want nil", *got)
+			case tt.want != nil && (got == nil || *got != *tt.want):
+				t.Errorf("ExitCode = %v, want %d", got, *tt.want)
+			}
+		})
+	}
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestSourceHash(t *testing.T) {
+	a := SourceHash("print(1)\n")
+	if len(a) != 12 {
+		t.Errorf("SourceHash length = %d, want 12", len(a))
+	}
+	if SourceHash("print(1)\n") != a {
+		t.Error("SourceHash is not stable for identical sources")
+	}
+	if SourceHash("print(2)\n") == a {
+		t.Error("SourceHash is the same for different sources")
+	}
+}
@@ -1,6 +1,27 @@
 package core
 
-import "flash-go/internal/models"
+import (
+	"flash-go/internal/models"
+	"flash-go/internal/utils"
+)
+
+// MaxProcessesLimit is the largest process/thread count a submission may request.
+const MaxProcessesLimit uint32 = 256
+
+// MaxStressRuns is the largest number of concurrent stress runs per submission.
+const MaxStressRuns uint32 = 16
+
+// MaxNumberOfRuns is the largest number_of_runs a submission may request.
+const MaxNumberOfRuns uint32 = 20
+
+// MaxDiskQuotaKB is the largest disk_quota_kb a submission may request.
+const MaxDiskQuotaKB uint64 = 1 << 20
+
+// Default per-stream output caps (MAX_STDOUT_BYTES, MAX_STDERR_BYTES).
+var (
+	defaultMaxStdoutBytes = utils.EnvInt64("MAX_STDOUT_BYTES", 1<<20)
+	defaultMaxStderrBytes = utils.EnvInt64("MAX_STDERR_BYTES", 1<<20)
+)
 
 // DefaultExecutionSettings returns the default resource limits used by the server.
 func DefaultExecutionSettings() models.ExecutionSettings {
@@ -19,5 +40,35 @@ func DefaultExecutionSettings() models.ExecutionSettings {
 		EnablePerProcessAndThreadTimeLimit:   false,
 		EnablePerProcessAndThreadMemoryLimit: false,
 		RedirectStderrToStdout:               false,
+		MaxOutputBytes:                       8 << 20,
+		MaxStdoutBytes:                       defaultMaxStdoutBytes,
+		MaxStderrBytes:                       defaultMaxStderrBytes,
+	}
+}
+
+// SettingsFor returns the starting limits for a language: its own defaults if
+// it defines any, otherwise DefaultExecutionSettings. Request overrides are
+// applied on top by the caller.
+func SettingsFor(lang models.Language) models.ExecutionSettings {
+	if lang.DefaultSettings != nil {
+		return *lang.DefaultSettings
 	}
+	return DefaultExecutionSettings()
+}
+
+// javaSettings gives the JVM more memory and twice the default wall time.
+func javaSettings() *models.ExecutionSettings {
+	settings := DefaultExecutionSettings()
+	settings.MemoryLimit = 256_000
+	settings.WallTimeLimit *= 2
+	return &settings
+}
+
+// kotlinSettings starts from the JVM defaults and raises the default wall
+// time to the maximum, since kotlinc is slow to start. The maximum itself is
+// the deployment cap on requests and stays unchanged.
+func kotlinSettings() *models.ExecutionSettings {
+	settings := javaSettings()
+	settings.WallTimeLimit = settings.MaxWallTimeLimit
+	return settings
 }
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"testing"
+
+	"flash-go/internal/models"
+)
+
+func TestSettingsFor(t *testing.T) {
+	defaults := DefaultExecutionSettings()
+
+	python := SettingsFor(languages["python"])
+	if python != defaults {
+		t.Errorf("python settings differ from the defaults: %+v", python)
+	}
+
+	java := SettingsFor(languages["java"])
+	if java.MemoryLimit != 256_000 {
+		t.Errorf("java memory_limit = %d, want 256000", java.MemoryLimit)
+	}
+	if java.WallTimeLimit != 2*defaults.WallTimeLimit {
+		t.Errorf("java wall_time_limit = %v, want %v", java.WallTimeLimit, 2*defaults.WallTimeLimit)
+	}
+	if java.CPUTimeLimit != defaults.CPUTimeLimit {
+		t.Errorf("java cpu_time_limit = %v, want the default %v", java.CPUTimeLimit, defaults.CPUTimeLimit)
+	}
+
+	kotlin := SettingsFor(languages["kotlin"])
+	if kotlin.WallTimeLimit != defaults.MaxWallTimeLimit || kotlin.WallTimeLimit < java.WallTimeLimit {
+		t.Errorf("kotlin wall_time_limit = %v, want the maximum %v", kotlin.WallTimeLimit, defaults.MaxWallTimeLimit)
+	}
+	if kotlin.MaxWallTimeLimit != defaults.MaxWallTimeLimit {
+		t.Errorf("kotlin max_wall_time_limit = %v, want the default cap %v", kotlin.MaxWallTimeLimit, defaults.MaxWallTimeLimit)
+	}
+
+	// Callers apply request overrides to a copy; the language defaults must
+	// not change underneath other requests.
+	java.MemoryLimit = 64_000
+	if SettingsFor(languages["java"]).MemoryLimit != 256_000 {
+		t.Error("overriding a returned settings value changed the language defaults")
+	}
+}
+
+func TestNewChecker(t *testing.T) {
+	checker, err := NewChecker(models.CheckerRequest{Code: "print(1)", Language: "python"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checker.Language.Name != "python" || checker.Settings != DefaultExecutionSettings() {
+		t.Errorf("unexpected checker %+v", checker)
+	}
+	if _, err := NewChecker(models.CheckerRequest{Code: " \n", Language: "python"}); err == nil {
+		t.Error("empty checker code accepted")
+	}
+	if _, err := NewChecker(models.CheckerRequest{Code: "x", Language: "cobol"}); err == nil {
+		t.Error("unknown checker language accepted")
+	}
+}
@@ -0,0 +1,36 @@
+package core
+
+import "fmt"
+
+// TimestampUnit is the unit job timestamps are reported in. Jobs always store
+// nanoseconds; the unit only affects API responses.
+type TimestampUnit string
+
+const (
+	TimestampNanoseconds  TimestampUnit = "ns"
+	TimestampMilliseconds TimestampUnit = "ms"
+	TimestampSeconds      TimestampUnit = "s"
+)
+
+// ParseTimestampUnit validates a configured timestamp unit.
+func ParseTimestampUnit(s string) (TimestampUnit, error) {
+	switch unit := TimestampUnit(s); unit {
+	case TimestampNanoseconds, TimestampMilliseconds, TimestampSeconds:
+		return unit, nil
+	default:
+		return "", fmt.Errorf("unknown timestamp unit %q (want ns, ms or s)", s)
+	}
+}
+
+// Scale converts a nanosecond timestamp into the unit. Zero stays zero so
+// unset timestamps remain recognisable.
+func (u TimestampUnit) Scale(ns int64) int64 {
+	switch u {
+	case TimestampMilliseconds:
+		return ns / 1_000_000
+	case TimestampSeconds:
+		return ns / 1_000_000_000
+	default:
+		return ns
+	}
+}
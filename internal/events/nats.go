@@ -0,0 +1,38 @@
+package events
+
+import (
+	"context"
+
+	"github.com/goccy/go-json"
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes events as JSON to a NATS subject.
+type NATSSink struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSSink connects to the NATS server at url.
+func NewNATSSink(url, subject string) (*NATSSink, error) {
+	conn, err := nats.Connect(url, nats.Name("flash-go"), nats.Timeout(publishTimeout))
+	if err != nil {
+		return nil, err
+	}
+	return &NATSSink{conn: conn, subject: subject}, nil
+}
+
+func (s *NATSSink) PublishJobFinished(_ context.Context, event JobFinished) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.conn.Publish(s.subject, payload)
+}
+
+// Close flushes pending messages and closes the connection.
+func (s *NATSSink) Close() error {
+	err := s.conn.FlushTimeout(publishTimeout)
+	s.conn.Close()
+	return err
+}
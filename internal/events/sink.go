@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"flash-go/internal/models"
+)
+
+// JobFinished is published once per job that reaches a terminal status.
+type JobFinished struct {
+	Token      uint64   `json:"token"`
+	Language   string   `json:"language"`
+	StatusID   int      `json:"status_id"`
+	Verdict    string   `json:"verdict"`
+	Time       *float64 `json:"time"`
+	Memory     uint64   `json:"memory"`
+	FinishedAt int64    `json:"finished_at"`
+}
+
+// NewJobFinished builds the event for a finished job.
+func NewJobFinished(job *models.Job) JobFinished {
+	var execTime *float64
+	if job.Output.TimeAvailable {
+		t := job.Output.Time
+		execTime = &t
+	}
+	return JobFinished{
+		Token:      job.ID,
+		Language:   job.Language.Name,
+		StatusID:   job.Status.ID(),
+		Verdict:    job.Status.Description(),
+		Time:       execTime,
+		Memory:     job.Output.Memory,
+		FinishedAt: job.FinishedAt,
+	}
+}
+
+// EventSink receives execution events for downstream consumers.
+type EventSink interface {
+	PublishJobFinished(ctx context.Context, event JobFinished) error
+	Close() error
+}
+
+// NopSink discards every event. It is the default sink.
+type NopSink struct{}
+
+func (NopSink) PublishJobFinished(context.Context, JobFinished) error { return nil }
+
+func (NopSink) Close() error { return nil }
+
+// publishTimeout bounds how long a sink may block the caller.
+const publishTimeout = 5 * time.Second
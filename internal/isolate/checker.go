@@ -0,0 +1,81 @@
+package isolate
+
+import (
+	"context"
+	"strings"
+
+	"flash-go/internal/models"
+)
+
+// checkerIDBit marks the ID a checker runs under, giving it its own box in
+// non-pool mode without colliding with the submission's.
+const checkerIDBit = 1 << 63
+
+// executeChecked runs the submission and, if it ran cleanly, judges its
+// output with the job's checker. The checker is called with the input,
+// actual output and expected output files as arguments.
+func (e *Executor) executeChecked(ctx context.Context, job *models.Job) (models.JobStatus, error) {
+	expected := job.ExpectedOutput
+	// The checker judges the output, so the plain comparison is skipped.
+	job.ExpectedOutput = ""
+	_, err := e.executeOnce(ctx, job)
+	job.ExpectedOutput = expected
+	if err != nil || job.Status.Kind != models.StatusAccepted {
+		return job.Status, err
+	}
+
+	checker := checkerJob(job, map[string]string{
+		models.CheckerInputFile:    job.Stdin,
+		models.CheckerOutputFile:   job.Output.Stdout,
+		models.CheckerExpectedFile: expected,
+	}, models.CheckerInputFile, models.CheckerOutputFile, models.CheckerExpectedFile)
+	_, checkerErr := e.executeOnce(ctx, &checker)
+	e.Cleanup(checker.ID)
+	job.FinishedAt = max(job.FinishedAt, checker.FinishedAt)
+	return applyCheckerVerdict(job, &checker, checkerErr)
+}
+
+// checkerJob builds the job that runs job's checker with files written into
+// its box and args appended to its run command.
+func checkerJob(job *models.Job, files map[string]string, args ...string) models.Job {
+	lang := job.Checker.Language
+	if len(args) > 0 {
+		lang.RunCmd += " " + strings.Join(args, " ")
+	}
+	return models.Job{
+		ID:              job.ID | checkerIDBit,
+		SourceCode:      job.Checker.SourceCode,
+		Language:        lang,
+		Settings:        job.Checker.Settings,
+		Status:          models.JobStatus{Kind: models.StatusProcessing},
+		AdditionalFiles: files,
+	}
+}
+
+// applyCheckerVerdict sets job's status from the finished checker: accepted
+// when it exits 0, wrong answer on any other exit code, and an internal
+// error if the checker failed to compile or was killed. The checker's stderr
+// becomes the job's message.
+func applyCheckerVerdict(job, checker *models.Job, checkerErr error) (models.JobStatus, error) {
+	if feedback := strings.TrimSpace(checker.Output.Stderr); feedback != "" {
+		job.Output.Message = feedback
+	}
+	switch {
+	case checkerErr != nil:
+		job.Status = models.JobStatus{Kind: models.StatusInternalError}
+		job.Output.Message = "checker failed: " + checkerErr.Error()
+		return job.Status, checkerErr
+	case checker.Status.Kind == models.StatusAccepted:
+		job.Status = models.JobStatus{Kind: models.StatusAccepted}
+	case checker.Status.Kind == models.StatusRuntimeError && checker.Status.RuntimeCode == "NZEC",
+		checker.Status.Kind == models.StatusCustom:
+		job.Status = models.JobStatus{Kind: models.StatusWrongAnswer}
+	default:
+		job.Status = models.JobStatus{Kind: models.StatusInternalError}
+		job.Output.Message = "checker failed: " + checker.Status.Description()
+		if checker.Output.CompileOutput != "" {
+			job.Output.Message += "\n" + checker.Output.CompileOutput
+		}
+	}
+	return job.Status, nil
+}
@@ -0,0 +1,63 @@
+package isolate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"sync/atomic"
+)
+
+// ErrPermanent marks execution errors that will fail the same way on retry,
+// such as a misconfigured language or an invalid submission.
+var ErrPermanent = errors.New("permanent execution error")
+
+// permanentError wraps err so IsPermanent reports true for it.
+type permanentError struct {
+	err error
+}
+
+func (p permanentError) Error() string { return p.err.Error() }
+
+func (p permanentError) Unwrap() []error { return []error{p.err, ErrPermanent} }
+
+func permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether retrying the job cannot help.
+func IsPermanent(err error) bool {
+	return errors.Is(err, ErrPermanent) || errors.Is(err, exec.ErrNotFound)
+}
+
+// ErrIsolateMissing is returned when the isolate binary cannot be executed.
+var ErrIsolateMissing = errors.New("isolate binary not found")
+
+// isolateMissing is set while the last attempt to start isolate failed
+// because the binary was missing.
+var isolateMissing atomic.Bool
+
+// Available reports whether the isolate binary could be started on the most
+// recent attempt. It is true until an attempt fails.
+func Available() bool {
+	return !isolateMissing.Load()
+}
+
+// checkBinary converts a failure to start isolate into ErrIsolateMissing and
+// records the executor as unhealthy. Other errors are returned unchanged.
+func checkBinary(err error) error {
+	if err == nil {
+		isolateMissing.Store(false)
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		isolateMissing.Store(false)
+		return err
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		isolateMissing.Store(true)
+		return permanent(fmt.Errorf("%w at %s", ErrIsolateMissing, isolatePath))
+	}
+	return err
+}
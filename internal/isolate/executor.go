@@ -1,17 +1,22 @@
 package isolate
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"flash-go/internal/metrics"
 	"flash-go/internal/models"
 	"flash-go/internal/utils"
 
@@ -24,10 +29,84 @@ const (
 )
 var useCgroup = utils.DetectCgroupSupport()
 
+// boxIDRange is the number of isolate box IDs used in non-pool mode. It
+// should match the boxes provisioned for isolate so cleanup can enumerate them.
+var boxIDRange = boxRangeFromEnv()
+
+func boxRangeFromEnv() uint64 {
+	n := utils.EnvInt64("ISOLATE_BOX_COUNT", boxModulo)
+	if n < 1 || n > boxModulo {
+		return boxModulo
+	}
+	return uint64(n)
+}
+
+// memoryUnit is the unit this isolate build reports memory in
+// (ISOLATE_MEMORY_UNIT, "kb" or "bytes"). Reported memory is always in KB.
+var memoryUnit = memoryUnitFromEnv()
+
+func memoryUnitFromEnv() utils.MemoryUnit {
+	unit, err := utils.ParseMemoryUnit(utils.EnvString("ISOLATE_MEMORY_UNIT", "kb"))
+	if err != nil {
+		logrus.WithError(err).Warn("invalid ISOLATE_MEMORY_UNIT, assuming kb")
+		return utils.MemoryUnitKB
+	}
+	return unit
+}
+
+// totalWallBudget bounds compile plus run wall time for one submission;
+// 0 disables it.
+var totalWallBudget = time.Duration(utils.EnvInt("TOTAL_WALL_TIME_LIMIT_SECONDS", 0)) * time.Second
+
+// totalBudgetMessage is reported when totalWallBudget runs out.
+const totalBudgetMessage = "total time budget exceeded"
+
+// execTimeoutMargin is added to a job's wall time limits to get the deadline
+// after which a hung isolate is killed (EXEC_TIMEOUT_MARGIN_SECONDS).
+var execTimeoutMargin = time.Duration(utils.EnvInt("EXEC_TIMEOUT_MARGIN_SECONDS", 10)) * time.Second
+
+// killWaitDelay bounds how long a killed isolate may keep its output pipes open.
+const killWaitDelay = 5 * time.Second
+
+// executionTimeoutMessage is reported when a job outlives its execution deadline.
+const executionTimeoutMessage = "execution timed out"
+
+// errExecutionTimeout is returned when a job outlived its execution deadline.
+var errExecutionTimeout = errors.New("isolate did not finish before the execution deadline")
+
+// compileOutputOnFailureOnly discards compile output of successful builds
+// to save storage.
+var compileOutputOnFailureOnly = utils.EnvBool("COMPILE_OUTPUT_ON_FAILURE_ONLY", false)
+
+// sandboxPath is the PATH inside the sandbox (SANDBOX_PATH), for toolchains
+// installed outside the standard directories.
+var sandboxPath = utils.EnvString("SANDBOX_PATH", "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin")
+
+// sandboxHome is HOME inside the sandbox.
+const sandboxHome = "/tmp"
+
+// useDiskQuota limits each box's disk usage with isolate quotas
+// (ENABLE_DISK_QUOTA). The box filesystem must be mounted with quota
+// support, so it is off by default.
+var useDiskQuota = utils.EnvBool("ENABLE_DISK_QUOTA", false)
+
+// diskQuotaKB is the box disk quota for jobs without their own
+// (DISK_QUOTA_KB), and diskQuotaInodes caps the number of files in a box
+// (DISK_QUOTA_INODES).
+var (
+	diskQuotaKB     = utils.EnvInt64("DISK_QUOTA_KB", 64<<10)
+	diskQuotaInodes = utils.EnvInt64("DISK_QUOTA_INODES", 4096)
+)
+
+// outputLimit caps how many bytes of stdout/stderr are read back for jobs
+// without their own per-stream limits.
+var outputLimit = utils.EnvInt64("OUTPUT_LIMIT_BYTES", 1<<20)
+
 type boxHandle struct {
-	id   uint64
-	path string
-	mu   sync.Mutex
+	id       uint64
+	path     string
+	mu       sync.Mutex
+	lastUsed time.Time
 }
 
 func (b *boxHandle) initIfNeeded(ctx context.Context) error {
@@ -36,7 +115,7 @@ func (b *boxHandle) initIfNeeded(ctx context.Context) error {
 	if b.path != "" {
 		return nil
 	}
-	boxPath, err := initBox(ctx, b.id)
+	boxPath, err := initBox(ctx, b.id, 0)
 	if err != nil {
 		return err
 	}
@@ -44,25 +123,62 @@ func (b *boxHandle) initIfNeeded(ctx context.Context) error {
 	return nil
 }
 
+// boxKey identifies a non-pool box reservation: the job and which of its
+// concurrent stress runs holds the box. Plain executions are run 0.
+type boxKey struct {
+	jobID uint64
+	run   int
+}
+
 type Executor struct {
 	pool    chan *boxHandle
 	usePool bool
+
+	// Dynamic sizing state; see EnableDynamicPool.
+	poolMu   sync.Mutex
+	poolSize int
+	maxPool  int
+	created  int
+	freeIDs  []uint64
+
+	// Non-pool box reservations, keyed both ways so Cleanup can find a
+	// job's box and collisions can be detected.
+	boxMu     sync.Mutex
+	boxOwners map[uint64]boxKey
+	jobBoxes  map[boxKey]uint64
+
+	// poolReady is set once every base pool box is initialized.
+	poolReady atomic.Bool
+
+	// Partial stdout streaming; see EnablePartialOutput.
+	partialInterval time.Duration
+	partialFlush    PartialOutputFunc
 }
 
-// NewExecutor creates an isolate executor with a reusable box pool.
+// NewExecutor creates an isolate executor with a reusable box pool. Pool
+// boxes are initialized in the background; see PoolReady.
 func NewExecutor(poolSize int, usePool bool) *Executor {
 	executor := &Executor{usePool: usePool}
 	if !usePool {
+		executor.boxOwners = make(map[uint64]boxKey)
+		executor.jobBoxes = make(map[boxKey]uint64)
+		executor.poolReady.Store(true)
 		return executor
 	}
 	if poolSize < 1 {
 		poolSize = 1
 	}
 	pool := make(chan *boxHandle, poolSize)
-	for i := 0; i < poolSize; i++ {
-		pool <- &boxHandle{id: uint64(i + 1)}
+	boxes := make([]*boxHandle, poolSize)
+	for i := range boxes {
+		boxes[i] = &boxHandle{id: uint64(i + 1)}
+		pool <- boxes[i]
 	}
 	executor.pool = pool
+	executor.poolSize = poolSize
+	executor.maxPool = poolSize
+	executor.created = poolSize
+	go executor.warmup(boxes)
 	return executor
 }
 
@@ -70,27 +186,154 @@ func (e *Executor) acquireBox(ctx context.Context) (*boxHandle, error) {
 	if !e.usePool || e.pool == nil {
 		return nil, errors.New("executor pool is not enabled")
 	}
+	var box *boxHandle
 	select {
-	case box := <-e.pool:
-		if err := box.initIfNeeded(ctx); err != nil {
-			e.pool <- box
-			return nil, err
+	case box = <-e.pool:
+	default:
+		// Pool is empty: grow it if allowed, otherwise wait for a release.
+		if box = e.growPool(); box == nil {
+			select {
+			case box = <-e.pool:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
 		}
-		return box, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
 	}
+	if err := box.initIfNeeded(ctx); err != nil {
+		e.releaseBox(box)
+		return nil, err
+	}
+	return box, nil
 }
 
 func (e *Executor) releaseBox(box *boxHandle) {
 	if box == nil || e.pool == nil {
 		return
 	}
+	box.lastUsed = time.Now()
 	e.pool <- box
 }
 
 func (e *Executor) Execute(ctx context.Context, job *models.Job) (models.JobStatus, error) {
+	if job.Settings.CompileOnly {
+		// Compiling once tells the whole story; stress copies would only
+		// repeat it.
+		return e.executeOnce(ctx, job)
+	}
+	if job.Checker != nil {
+		return e.executeWithChecker(ctx, job)
+	}
+	if job.Settings.StressRuns > 1 {
+		return e.executeStress(ctx, job)
+	}
+	return e.executeOnce(ctx, job)
+}
+
+// executeWithChecker runs a job judged by a checker. Each box still gets its
+// own execution deadline; this one bounds the submission and checker
+// together so a checker job cannot outlive its budget across phases.
+func (e *Executor) executeWithChecker(ctx context.Context, job *models.Job) (models.JobStatus, error) {
+	checker := checkerJob(job, nil)
+	timeout := executionTimeout(job)
+	if job.Checker.Interactive {
+		// Both programs run side by side.
+		timeout = max(timeout, executionTimeout(&checker))
+	} else {
+		timeout += executionTimeout(&checker)
+	}
+	jobCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		status models.JobStatus
+		err    error
+	)
+	if job.Checker.Interactive {
+		status, err = e.executeInteractive(jobCtx, job)
+	} else {
+		status, err = e.executeChecked(jobCtx, job)
+	}
+	if ctx.Err() == nil && errors.Is(jobCtx.Err(), context.DeadlineExceeded) {
+		job.Status = models.JobStatus{Kind: models.StatusInternalError}
+		job.Output.Message = executionTimeoutMessage
+		job.FinishedAt = time.Now().UnixNano()
+		logFailedJob("checked execution timed out", job, job.Output.BoxID)
+		return job.Status, errExecutionTimeout
+	}
+	return status, err
+}
+
+// executeStress runs the job StressRuns times concurrently, each in its own
+// box. The job keeps the first run's output and the first non-accepted
+// verdict, plus the distinct verdicts observed across all runs.
+func (e *Executor) executeStress(ctx context.Context, job *models.Job) (models.JobStatus, error) {
+	status, err := stress(ctx, job, func(ctx context.Context, run *models.Job, i int) error {
+		_, err := e.executeWith(ctx, run, i, runJob)
+		return err
+	})
+	for i := 1; i < int(job.Settings.StressRuns); i++ {
+		e.cleanup(boxKey{jobID: job.ID, run: i})
+	}
+	return status, err
+}
+
+// stress runs StressRuns copies of job concurrently with exec and merges
+// their results into job. Every copy keeps the job's ID; exec tells them
+// apart by their run index.
+func stress(ctx context.Context, job *models.Job, exec func(ctx context.Context, run *models.Job, i int) error) (models.JobStatus, error) {
+	runs := make([]models.Job, job.Settings.StressRuns)
+	errs := make([]error, len(runs))
+	var wg sync.WaitGroup
+	for i := range runs {
+		runs[i] = *job
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = exec(ctx, &runs[i], i)
+		}(i)
+	}
+	wg.Wait()
+
+	job.Output = runs[0].Output
+	job.Status = runs[0].Status
+	job.FinishedAt = runs[0].FinishedAt
+	seen := make(map[string]bool, len(runs))
+	verdicts := make([]string, 0, len(runs))
+	for _, run := range runs {
+		verdict := run.Status.Description()
+		if !seen[verdict] {
+			seen[verdict] = true
+			verdicts = append(verdicts, verdict)
+		}
+		if job.Status.Kind == models.StatusAccepted && run.Status.Kind != models.StatusAccepted {
+			job.Status = run.Status
+		}
+		if run.FinishedAt > job.FinishedAt {
+			job.FinishedAt = run.FinishedAt
+		}
+	}
+	job.Output.StressVerdicts = verdicts
+	job.Output.StressDivergent = len(verdicts) > 1
 
+	for i, err := range errs {
+		if err != nil {
+			logFailedJob("stress run returned internal error", &runs[i], 0)
+			return job.Status, err
+		}
+	}
+	return job.Status, nil
+}
+
+// runFunc runs a compiled job inside its box.
+type runFunc func(ctx context.Context, job *models.Job, boxID uint64, paths models.JobPaths) error
+
+func (e *Executor) executeOnce(ctx context.Context, job *models.Job) (models.JobStatus, error) {
+	return e.executeWith(ctx, job, 0, runJob)
+}
+
+// executeWith prepares a box for stress run stressRun of job, compiles it if
+// needed and runs it with run. Jobs that are not stress tested are run 0.
+func (e *Executor) executeWith(ctx context.Context, job *models.Job, stressRun int, run runFunc) (models.JobStatus, error) {
 	var (
 		boxID   uint64
 		boxPath string
@@ -98,7 +341,9 @@ func (e *Executor) Execute(ctx context.Context, job *models.Job) (models.JobStat
 		err     error
 	)
 	if e.usePool {
+		waitStart := time.Now()
 		box, err = e.acquireBox(ctx)
+		metrics.BoxAcquireWait.Observe(time.Since(waitStart).Seconds())
 		if err != nil {
 			job.Status = models.JobStatus{Kind: models.StatusInternalError}
 			job.Output.Message = err.Error()
@@ -119,8 +364,15 @@ func (e *Executor) Execute(ctx context.Context, job *models.Job) (models.JobStat
 		boxID = box.id
 		boxPath = box.path
 	} else {
-		boxID = job.ID % boxModulo
-		boxPath, err = initBox(ctx, boxID)
+		boxID, err = e.reserveBox(boxKey{jobID: job.ID, run: stressRun})
+		if err != nil {
+			job.Status = models.JobStatus{Kind: models.StatusInternalError}
+			job.Output.Message = err.Error()
+			job.FinishedAt = time.Now().UnixNano()
+			logFailedJob("failed to reserve box", job, boxID)
+			return job.Status, err
+		}
+		boxPath, err = initBox(ctx, boxID, job.Settings.DiskQuotaKB)
 		if err != nil {
 			job.Status = models.JobStatus{Kind: models.StatusInternalError}
 			job.Output.Message = err.Error()
@@ -130,6 +382,7 @@ func (e *Executor) Execute(ctx context.Context, job *models.Job) (models.JobStat
 		}
 	}
 
+	job.Output.BoxID = boxID
 	paths, err := setupFiles(job, boxPath)
 	if err != nil {
 		job.Status = models.JobStatus{Kind: models.StatusInternalError}
@@ -139,8 +392,43 @@ func (e *Executor) Execute(ctx context.Context, job *models.Job) (models.JobStat
 		return job.Status, err
 	}
 
-	if job.Language.CompileCmd != "" {
+	// A hung isolate would otherwise block the worker forever; the deadline
+	// leaves every legitimate step its full wall time.
+	runCtx, cancel := context.WithTimeout(ctx, executionTimeout(job))
+	defer cancel()
+	// Only the first stress run streams partial output; the copies share its
+	// job ID and would overwrite it.
+	status, err := e.executeInBox(runCtx, job, boxID, paths, run, stressRun == 0)
+	if ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		job.Status = models.JobStatus{Kind: models.StatusInternalError}
+		job.Output.Message = executionTimeoutMessage
+		job.FinishedAt = time.Now().UnixNano()
+		logFailedJob("execution timed out", job, boxID)
+		return job.Status, errExecutionTimeout
+	}
+	return status, err
+}
+
+// executeInBox compiles the job if needed and runs it with run in a box
+// prepared by executeWith. Partial stdout is streamed only when tail is set.
+func (e *Executor) executeInBox(ctx context.Context, job *models.Job, boxID uint64, paths models.JobPaths, run runFunc, tail bool) (models.JobStatus, error) {
+	// The budget only limits this submission; parentCtx still tells shutdown
+	// apart from an exhausted budget.
+	parentCtx := ctx
+	if totalWallBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, totalWallBudget)
+		defer cancel()
+	}
+	budgetExceeded := func() bool {
+		return parentCtx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
+	}
+
+	if job.Language.IsCompiled && !job.Settings.RunOnly {
 		compileStatus, compileErr := compileJob(ctx, job, boxID, paths)
+		if budgetExceeded() {
+			return budgetExceededStatus(job), nil
+		}
 		if compileErr != nil {
 			job.Status = models.JobStatus{Kind: models.StatusInternalError}
 			job.Output.Message = compileErr.Error()
@@ -155,8 +443,23 @@ func (e *Executor) Execute(ctx context.Context, job *models.Job) (models.JobStat
 			return job.Status, nil
 		}
 	}
+	if job.Settings.CompileOnly {
+		// Interpreted languages have nothing to compile and are accepted as is.
+		job.Status = models.JobStatus{Kind: models.StatusAccepted}
+		job.FinishedAt = time.Now().UnixNano()
+		return job.Status, nil
+	}
 
-	runErr := runJob(ctx, job, boxID, paths)
+	stopTail := func() {}
+	if tail {
+		stopTail = e.tailStdout(job, paths.StdoutPath)
+	}
+	runErr := run(ctx, job, boxID, paths)
+	stopTail()
+	if budgetExceeded() {
+		_ = readOutputs(job, paths)
+		return budgetExceededStatus(job), nil
+	}
 	if runErr != nil && !errors.Is(runErr, context.DeadlineExceeded) {
 		job.Status = models.JobStatus{Kind: models.StatusInternalError}
 		job.Output.Message = runErr.Error()
@@ -173,7 +476,7 @@ func (e *Executor) Execute(ctx context.Context, job *models.Job) (models.JobStat
 		return job.Status, err
 	}
 
-	meta, err := utils.ReadMetadata(paths.MetadataPath)
+	meta, err := utils.ReadMetadata(paths.MetadataPath, memoryUnit)
 	if err != nil {
 		job.Status = models.JobStatus{Kind: models.StatusInternalError}
 		job.Output.Message = err.Error()
@@ -182,12 +485,45 @@ func (e *Executor) Execute(ctx context.Context, job *models.Job) (models.JobStat
 		return job.Status, err
 	}
 
+	if job.Settings.NumberOfRuns > 1 && meta.Status == "" {
+		err := repeatRuns(ctx, job, boxID, paths, run, &meta)
+		if budgetExceeded() {
+			return budgetExceededStatus(job), nil
+		}
+		if err != nil {
+			job.Status = models.JobStatus{Kind: models.StatusInternalError}
+			job.Output.Message = err.Error()
+			job.FinishedAt = time.Now().UnixNano()
+			logFailedJob("repeated run returned internal error", job, boxID)
+			return job.Status, err
+		}
+	}
+
+	// A cgroup run whose peak usage reached the limit was killed by the
+	// kernel OOM killer even if isolate did not say so.
+	if useCgroup && !job.Settings.EnablePerProcessAndThreadMemoryLimit &&
+		job.Settings.MemoryLimit > 0 && meta.Memory >= job.Settings.MemoryLimit {
+		meta.OOMKilled = true
+	}
+
 	job.Output.Time = meta.Time
+	job.Output.TimeAvailable = meta.HasTime
 	job.Output.Memory = meta.Memory
+	job.Output.CgMem = meta.CgMem
+	job.Output.MaxRSS = meta.MaxRSS
 	job.Output.ExitCode = meta.ExitCode
 	job.Output.Message = meta.Message
 
-	job.Status = utils.DetermineStatus(meta.Status, meta.ExitCode, job.Output.Stdout, job.ExpectedOutput)
+	stdout, expected := job.Output.Stdout, job.ExpectedOutput
+	if job.Settings.NormalizeLineEndings {
+		stdout, expected = utils.NormalizeLineEndings(stdout), utils.NormalizeLineEndings(expected)
+	}
+	if job.Status.Kind != models.StatusOutputLimitExceeded {
+		job.Status = utils.DetermineStatus(meta, stdout, expected, job.Settings.ComparisonMode, job.Settings.FloatTolerance, job.Settings.ReportPresentationError)
+	}
+	if job.Status.Kind == models.StatusWrongAnswer || job.Status.Kind == models.StatusPresentationError {
+		job.Output.Diff = utils.DiffOutput(stdout, expected, job.Settings.ComparisonMode)
+	}
 	job.FinishedAt = time.Now().UnixNano()
 	// if job.Status.Kind != models.StatusAccepted {
 	// 	logFailedJob("job finished with non-accepted status", job, boxID)
@@ -195,23 +531,77 @@ func (e *Executor) Execute(ctx context.Context, job *models.Job) (models.JobStat
 	return job.Status, nil
 }
 
+// repeatRuns runs the job NumberOfRuns-1 more times after a successful first
+// run, reusing the compiled program, and stores the average time and memory
+// of all runs in meta. Output of the extra runs is discarded. Runs stop early
+// if one fails, and only the runs so far are averaged.
+func repeatRuns(ctx context.Context, job *models.Job, boxID uint64, paths models.JobPaths, run runFunc, meta *utils.Metadata) error {
+	runs := 1
+	totalTime := meta.Time
+	totalMem, totalCgMem, totalRSS := meta.Memory, meta.CgMem, meta.MaxRSS
+	for ; runs < int(job.Settings.NumberOfRuns); runs++ {
+		if err := run(ctx, job, boxID, paths); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		next, err := utils.ReadMetadata(paths.MetadataPath, memoryUnit)
+		if err != nil {
+			return err
+		}
+		if next.Status != "" {
+			break
+		}
+		totalTime += next.Time
+		totalMem += next.Memory
+		totalCgMem += next.CgMem
+		totalRSS += next.MaxRSS
+	}
+	meta.Time = totalTime / float64(runs)
+	meta.Memory = totalMem / uint64(runs)
+	meta.CgMem = totalCgMem / uint64(runs)
+	meta.MaxRSS = totalRSS / uint64(runs)
+	return nil
+}
+
+// executionTimeout bounds one execution: the compile and run wall time
+// limits, once per run, plus execTimeoutMargin.
+func executionTimeout(job *models.Job) time.Duration {
+	runs := max(job.Settings.NumberOfRuns, 1)
+	limit := job.Settings.WallTimeLimit * float64(runs)
+	if job.Language.IsCompiled && !job.Settings.RunOnly {
+		limit += job.Settings.MaxWallTimeLimit
+	}
+	return time.Duration(limit*float64(time.Second)) + execTimeoutMargin
+}
+
+// budgetExceededStatus marks job as out of its total wall time budget.
+func budgetExceededStatus(job *models.Job) models.JobStatus {
+	job.Status = models.JobStatus{Kind: models.StatusTimeLimitExceeded}
+	job.Output.Message = totalBudgetMessage
+	job.FinishedAt = time.Now().UnixNano()
+	return job.Status
+}
+
+// Cleanup tears down the job's box in the background and frees its ID once
+// isolate is done with it. It is a no-op in pool mode.
 func (e *Executor) Cleanup(jobID uint64) {
+	e.cleanup(boxKey{jobID: jobID})
+}
+
+// cleanup is Cleanup for a single reservation, such as one stress run.
+func (e *Executor) cleanup(key boxKey) {
 	if e.usePool {
 		return
 	}
-	boxID := jobID % boxModulo
-	boxIDStr := strconv.FormatUint(boxID, 10)
-	
-	args := []string{"-b", boxIDStr}
-	if useCgroup {
-		args = append([]string{"--cg"}, args...)
+	boxID, ok := e.detachBox(key)
+	if !ok {
+		return
 	}
-	args = append(args, "--cleanup")
-	
-	cmd := exec.Command(isolatePath, args...)
-	_ = cmd.Start()
 	go func() {
-		_ = cmd.Wait()
+		cleanupBox(boxID)
+		e.releaseBoxID(boxID)
 	}()
 }
 
@@ -219,25 +609,95 @@ func (e *Executor) CleanupSync(jobID uint64) {
 	if e.usePool {
 		return
 	}
-	boxID := jobID % boxModulo
-	
+	boxID, ok := e.detachBox(boxKey{jobID: jobID})
+	if !ok {
+		return
+	}
+	cleanupBox(boxID)
+	e.releaseBoxID(boxID)
+}
+
+// reserveBox picks a box ID for key in non-pool mode. It starts at the job ID
+// plus the stress run modulo the box range and probes upward past boxes still
+// held by other reservations, so two jobs whose IDs collide modulo the range,
+// or two runs of one job, never share a box.
+func (e *Executor) reserveBox(key boxKey) (uint64, error) {
+	e.boxMu.Lock()
+	defer e.boxMu.Unlock()
+	if boxID, ok := e.jobBoxes[key]; ok {
+		return boxID, nil
+	}
+	start := (key.jobID + uint64(key.run)) % boxIDRange
+	for i := uint64(0); i < boxIDRange; i++ {
+		boxID := (start + i) % boxIDRange
+		if _, taken := e.boxOwners[boxID]; taken {
+			continue
+		}
+		if i > 0 {
+			metrics.BoxCollisions.Inc()
+			logrus.WithFields(logrus.Fields{
+				"job_id":    key.jobID,
+				"run":       key.run,
+				"preferred": start,
+				"box_id":    boxID,
+				"owner":     e.boxOwners[start].jobID,
+			}).Debug("isolate box collision, using next free box")
+		}
+		e.boxOwners[boxID] = key
+		e.jobBoxes[key] = boxID
+		return boxID, nil
+	}
+	return 0, errors.New("no free isolate box")
+}
+
+// detachBox forgets the reservation's box so a retry reserves a fresh one.
+// The box itself stays reserved until releaseBoxID is called after cleanup.
+func (e *Executor) detachBox(key boxKey) (uint64, bool) {
+	e.boxMu.Lock()
+	defer e.boxMu.Unlock()
+	boxID, ok := e.jobBoxes[key]
+	delete(e.jobBoxes, key)
+	return boxID, ok
+}
+
+func (e *Executor) releaseBoxID(boxID uint64) {
+	e.boxMu.Lock()
+	defer e.boxMu.Unlock()
+	delete(e.boxOwners, boxID)
+}
+
+// cleanupBox tears down an isolate box and waits for it to finish.
+func cleanupBox(boxID uint64) {
 	args := []string{"-b", strconv.FormatUint(boxID, 10)}
 	if useCgroup {
 		args = append([]string{"--cg"}, args...)
 	}
 	args = append(args, "--cleanup")
-	
+
 	_ = exec.Command(isolatePath, args...).Run()
 }
 
-func initBox(ctx context.Context, boxID uint64) (string, error) {
-	args := []string{"-b", strconv.FormatUint(boxID, 10), "--init"}
+// initBox initializes a box limited to quotaKB of disk, or the default quota
+// when it is 0, and returns its path.
+func initBox(ctx context.Context, boxID, quotaKB uint64) (string, error) {
+	args := []string{"-b", strconv.FormatUint(boxID, 10)}
 	if useCgroup {
 		args = append([]string{"--cg"}, args...)
 	}
+	args = append(args, quotaFlags(quotaKB)...)
+	args = append(args, "--init")
 	
-	cmd := exec.CommandContext(ctx, isolatePath, args...)
-	output, err := cmd.CombinedOutput()
+	output, err := exec.CommandContext(ctx, isolatePath, args...).CombinedOutput()
+	if err := checkBinary(err); errors.Is(err, ErrIsolateMissing) {
+		return "", err
+	}
+	if err != nil && boxExists(output) {
+		// A stale box is left over, e.g. from a crashed worker or a cleanup
+		// still in flight; tear it down and try once more.
+		logrus.WithField("box_id", boxID).Warn("isolate box already exists, cleaning up before init")
+		cleanupBox(boxID)
+		output, err = exec.CommandContext(ctx, isolatePath, args...).CombinedOutput()
+	}
 	if err != nil {
 		return "", fmt.Errorf("isolate init failed: %w (%s)", err, strings.TrimSpace(string(output)))
 	}
@@ -248,6 +708,23 @@ func initBox(ctx context.Context, boxID uint64) (string, error) {
 	return boxPath, nil
 }
 
+// quotaFlags returns the isolate flag setting a box's disk quota. isolate only
+// accepts it on --init, so the quota is fixed for the box's lifetime.
+func quotaFlags(quotaKB uint64) []string {
+	if !useDiskQuota {
+		return nil
+	}
+	if quotaKB == 0 {
+		quotaKB = uint64(diskQuotaKB)
+	}
+	return []string{"--quota=" + strconv.FormatUint(quotaKB, 10) + "," + strconv.FormatInt(diskQuotaInodes, 10)}
+}
+
+// boxExists reports whether isolate output says the box is already initialised.
+func boxExists(output []byte) bool {
+	return strings.Contains(strings.ToLower(string(output)), "already exists")
+}
+
 // cleanBoxContents removes all files and directories inside the box, but keeps the box structure intact.
 func cleanBoxContents(boxPath string) error {
 	boxDir := filepath.Join(boxPath, "box")
@@ -283,9 +760,31 @@ func setupFiles(job *models.Job, boxPath string) (models.JobPaths, error) {
 	if err := os.WriteFile(sourcePath, []byte(job.SourceCode), 0o644); err != nil {
 		return models.JobPaths{}, fmt.Errorf("write source: %w", err)
 	}
-	if err := os.WriteFile(stdinPath, []byte(job.Stdin), 0o644); err != nil {
+	if job.Settings.RunOnly {
+		if err := os.WriteFile(filepath.Join(boxDir, job.Language.Artifact), job.Binary, 0o755); err != nil {
+			return models.JobPaths{}, fmt.Errorf("write binary: %w", err)
+		}
+	}
+	stdin := job.Stdin
+	if job.Settings.NormalizeLineEndings {
+		stdin = utils.NormalizeLineEndings(stdin)
+	}
+	inputPath := stdinPath
+	if job.Settings.InputMode == models.InputModeFile {
+		inputPath = filepath.Join(boxDir, models.InputFile)
+	}
+	if err := os.WriteFile(inputPath, []byte(stdin), 0o644); err != nil {
 		return models.JobPaths{}, fmt.Errorf("write stdin: %w", err)
 	}
+	for name, content := range job.AdditionalFiles {
+		// Names are validated on submission; re-checked so nothing escapes the box.
+		if name != filepath.Base(name) || strings.Contains(name, "..") {
+			return models.JobPaths{}, permanent(fmt.Errorf("invalid additional file name %q", name))
+		}
+		if err := os.WriteFile(filepath.Join(boxDir, name), []byte(content), 0o644); err != nil {
+			return models.JobPaths{}, fmt.Errorf("write additional file %s: %w", name, err)
+		}
+	}
 
 	return models.JobPaths{
 		BoxPath:           boxPath,
@@ -297,6 +796,23 @@ func setupFiles(job *models.Job, boxPath string) (models.JobPaths, error) {
 	}, nil
 }
 
+// envFlags returns isolate flags setting the sandbox environment: the base
+// PATH and HOME followed by the job's environment variables, in a stable
+// order. Compile and run both use it so their environments cannot drift.
+func envFlags(job *models.Job) []string {
+	keys := make([]string, 0, len(job.Env))
+	for key := range job.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	flags := make([]string, 0, 4+2*len(keys))
+	flags = append(flags, "-E", "PATH="+sandboxPath, "-E", "HOME="+sandboxHome)
+	for _, key := range keys {
+		flags = append(flags, "-E", key+"="+job.Env[key])
+	}
+	return flags
+}
+
 // getCgroupFlags returns cgroup-related flags based on job settings
 func getCgroupFlags(job *models.Job, memoryLimit uint64) []string {
 	flags := []string{}
@@ -317,9 +833,16 @@ func getCgroupFlags(job *models.Job, memoryLimit uint64) []string {
 }
 
 func compileJob(ctx context.Context, job *models.Job, boxID uint64, paths models.JobPaths) (models.JobStatus, error) {
-	parts := strings.Fields(job.Language.CompileCmd)
+	parts := strings.Fields(job.Language.CompileCommand())
 	if len(parts) == 0 {
-		return models.JobStatus{Kind: models.StatusInternalError}, errors.New("compile command is empty")
+		return models.JobStatus{Kind: models.StatusInternalError}, permanent(errors.New("compile command is empty"))
+	}
+	if job.CompilerOptions != "" {
+		// Re-checked here as the command is run through sh -c.
+		if err := utils.ValidateCompilerOptions(job.CompilerOptions); err != nil {
+			return models.JobStatus{Kind: models.StatusInternalError}, permanent(err)
+		}
+		parts = insertCompilerOptions(parts, job.Language.SourceFile, strings.Fields(job.CompilerOptions))
 	}
 
 	sb := utils.GetStringBuilder()
@@ -328,7 +851,8 @@ func compileJob(ctx context.Context, job *models.Job, boxID uint64, paths models
 		sb.WriteByte(' ')
 		sb.WriteString(parts[i])
 	}
-	sb.WriteString(" 2> /box/compile_output")
+	// Some compilers (e.g. tsc) report diagnostics on stdout, so capture both.
+	sb.WriteString(" > /box/compile_output 2>&1")
 	cmdStr := sb.String()
 	utils.PutStringBuilder(sb)
 
@@ -357,10 +881,9 @@ func compileJob(ctx context.Context, job *models.Job, boxID uint64, paths models
 		"-w", wallTimeStr,
 		"-k", stackStr,
 		"-f", fileSizeStr,
-		"-E", "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
-		"-E", "HOME=/tmp",
 		"-d", "/etc:noexec",
 	)
+	args = append(args, envFlags(job)...)
 
 	cgFlags := getCgroupFlags(job, job.Settings.MaxMemoryLimit)
 	args = append(args, cgFlags...)
@@ -373,7 +896,12 @@ func compileJob(ctx context.Context, job *models.Job, boxID uint64, paths models
 		cmdStr,
 	)
 
-	output, err := exec.CommandContext(ctx, isolatePath, args...).CombinedOutput()
+	cmd := exec.CommandContext(ctx, isolatePath, args...)
+	cmd.WaitDelay = killWaitDelay
+	output, err := cmd.CombinedOutput()
+	if err := checkBinary(err); errors.Is(err, ErrIsolateMissing) {
+		return models.JobStatus{Kind: models.StatusInternalError}, err
+	}
 	compileOutput := utils.ReadFileIfExists(paths.CompileOutputPath)
 	if compileOutput != "" {
 		job.Output.CompileOutput = compileOutput
@@ -383,6 +911,13 @@ func compileJob(ctx context.Context, job *models.Job, boxID uint64, paths models
 		if compileOutput == "" {
 			job.Output.CompileOutput = strings.TrimSpace(string(output))
 		}
+		if msg, ok := compileTimeoutMessage(job, paths.MetadataPath); ok {
+			job.Output.Message = msg
+			if job.Output.CompileOutput == "" {
+				job.Output.CompileOutput = msg
+			}
+			return models.JobStatus{Kind: models.StatusCompilationError}, nil
+		}
 		if job.Output.CompileOutput != "" {
 			job.Output.Message = job.Output.CompileOutput
 		} else {
@@ -396,10 +931,40 @@ func compileJob(ctx context.Context, job *models.Job, boxID uint64, paths models
 	return models.JobStatus{Kind: models.StatusAccepted}, nil
 }
 
+// insertCompilerOptions places opts just before the source file argument so
+// compilers that stop parsing flags at the first input (e.g. go build) still
+// see them. They are appended when the source file is not named explicitly.
+func insertCompilerOptions(parts []string, sourceFile string, opts []string) []string {
+	for i, part := range parts {
+		if part == sourceFile {
+			out := make([]string, 0, len(parts)+len(opts))
+			out = append(out, parts[:i]...)
+			out = append(out, opts...)
+			return append(out, parts[i:]...)
+		}
+	}
+	return append(parts, opts...)
+}
+
 func runJob(ctx context.Context, job *models.Job, boxID uint64, paths models.JobPaths) error {
-	parts := strings.Fields(job.Language.RunCmd)
+	if job.Settings.InputMode == models.InputModeFile {
+		return runIsolate(ctx, job, boxID, paths, nil, nil)
+	}
+	stdinFile, err := os.Open(paths.StdinPath)
+	if err != nil {
+		return fmt.Errorf("open stdin: %w", err)
+	}
+	defer stdinFile.Close()
+	return runIsolate(ctx, job, boxID, paths, stdinFile, nil)
+}
+
+// runIsolate runs the job's run command in its box reading stdin. Stdout goes
+// to the box's stdout file, or to stdout when it is not nil. In file input mode
+// the input file name is appended to the command.
+func runIsolate(ctx context.Context, job *models.Job, boxID uint64, paths models.JobPaths, stdin io.Reader, stdout io.Writer) error {
+	parts := strings.Fields(job.Language.RunCommand())
 	if len(parts) == 0 {
-		return errors.New("run command is empty")
+		return permanent(errors.New("run command is empty"))
 	}
 
 	sb := utils.GetStringBuilder()
@@ -408,7 +973,14 @@ func runJob(ctx context.Context, job *models.Job, boxID uint64, paths models.Job
 		sb.WriteByte(' ')
 		sb.WriteString(parts[i])
 	}
-	sb.WriteString(" > /box/stdout 2> /box/stderr")
+	if job.Settings.InputMode == models.InputModeFile {
+		sb.WriteByte(' ')
+		sb.WriteString(models.InputFile)
+	}
+	if stdout == nil {
+		sb.WriteString(" > /box/stdout")
+	}
+	sb.WriteString(" 2> /box/stderr")
 	cmdStr := sb.String()
 	utils.PutStringBuilder(sb)
 
@@ -446,10 +1018,9 @@ func runJob(ctx context.Context, job *models.Job, boxID uint64, paths models.Job
 		"-w", wallTimeStr,
 		"-k", stackStr,
 		"-f", fileSizeStr,
-		"-E", "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
-		"-E", "HOME=/tmp",
 		"-d", "/etc:noexec",
 	)
+	args = append(args, envFlags(job)...)
 
 	cgFlags := getCgroupFlags(job, job.Settings.MemoryLimit)
 	args = append(args, cgFlags...)
@@ -463,34 +1034,71 @@ func runJob(ctx context.Context, job *models.Job, boxID uint64, paths models.Job
 	)
 
 	cmd := exec.CommandContext(ctx, isolatePath, args...)
-	stdinFile, err := os.Open(paths.StdinPath)
-	if err != nil {
-		return fmt.Errorf("open stdin: %w", err)
+	cmd.WaitDelay = killWaitDelay
+	var output bytes.Buffer
+	cmd.Stdin = stdin
+	cmd.Stdout, cmd.Stderr = &output, &output
+	if stdout != nil {
+		cmd.Stdout = stdout
 	}
-	defer stdinFile.Close()
-	cmd.Stdin = stdinFile
 
-	output, err := cmd.CombinedOutput()
+	err := cmd.Run()
+	if err := checkBinary(err); errors.Is(err, ErrIsolateMissing) {
+		return err
+	}
 	if err != nil {
 		if _, ok := err.(*exec.ExitError); ok {
 			return nil
 		}
-		return fmt.Errorf("isolate run failed: %w (%s)", err, strings.TrimSpace(string(output)))
+		return fmt.Errorf("isolate run failed: %w (%s)", err, strings.TrimSpace(output.String()))
 	}
 	return nil
 }
 
+// readOutputs loads the job's outputs. When their combined size on disk is
+// above Settings.MaxOutputBytes it marks the job OutputLimitExceeded.
 func readOutputs(job *models.Job, paths models.JobPaths) error {
-	job.Output.Stdout = utils.ReadFileIfExists(paths.StdoutPath)
-	job.Output.Stderr = utils.ReadFileIfExists(paths.StderrPath)
-	if job.Output.CompileOutput == "" && job.Language.CompileCmd != "" {
-		job.Output.CompileOutput = utils.ReadFileIfExists(paths.CompileOutputPath)
-	} else if job.Language.CompileCmd == "" {
+	if limit := job.Settings.MaxOutputBytes; limit > 0 {
+		total := fileSize(paths.StdoutPath) + fileSize(paths.StderrPath) + fileSize(paths.CompileOutputPath)
+		if total > limit {
+			job.Status = models.JobStatus{Kind: models.StatusOutputLimitExceeded}
+		}
+	}
+	stdout, stdoutTruncated := utils.ReadFileCapped(paths.StdoutPath, streamLimit(job.Settings.MaxStdoutBytes))
+	stderr, stderrTruncated := utils.ReadFileCapped(paths.StderrPath, streamLimit(job.Settings.MaxStderrBytes))
+	job.Output.Stdout = stdout
+	job.Output.Stderr = stderr
+	job.Output.StdoutTruncated = stdoutTruncated
+	job.Output.StderrTruncated = stderrTruncated
+	job.Output.Truncated = stdoutTruncated || stderrTruncated
+	// readOutputs only runs after a successful compile, so this is where
+	// compile output is dropped when it is only kept for failures.
+	if !job.Language.IsCompiled || compileOutputOnFailureOnly {
 		job.Output.CompileOutput = ""
+	} else if job.Output.CompileOutput == "" {
+		job.Output.CompileOutput = utils.ReadFileIfExists(paths.CompileOutputPath)
 	}
 	return nil
 }
 
+// streamLimit returns a job's cap for one output stream, falling back to
+// outputLimit for jobs queued without one.
+func streamLimit(limit int64) int64 {
+	if limit > 0 {
+		return limit
+	}
+	return outputLimit
+}
+
+// fileSize returns the size of path in bytes, or 0 if it cannot be stat'd.
+func fileSize(path string) uint64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return uint64(info.Size())
+}
+
 func previewForLog(s string, max int) string {
 	if max <= 0 || s == "" {
 		return ""
@@ -502,8 +1110,22 @@ func previewForLog(s string, max int) string {
 	return s[:max] + "..."
 }
 
+// compileTimeoutMessage reports whether the compile step hit its time limit
+// and, if so, which limit it was.
+func compileTimeoutMessage(job *models.Job, metadataPath string) (string, bool) {
+	meta, err := utils.ReadMetadata(metadataPath, memoryUnit)
+	if err != nil || meta.Status != "TO" {
+		return "", false
+	}
+	limit := job.Settings.MaxCPUTimeLimit
+	if strings.Contains(meta.Message, "wall") {
+		limit = job.Settings.MaxWallTimeLimit
+	}
+	return fmt.Sprintf("Compilation timed out after %ss", strconv.FormatFloat(limit, 'g', -1, 64)), true
+}
+
 func compileFailureMessageFromMetadata(metadataPath string) string {
-	meta, err := utils.ReadMetadata(metadataPath)
+	meta, err := utils.ReadMetadata(metadataPath, memoryUnit)
 	if err != nil {
 		return "Compilation failed (no output captured)."
 	}
@@ -528,9 +1150,10 @@ func logFailedJob(reason string, job *models.Job, boxID uint64) {
 		return
 	}
 	logrus.WithFields(logrus.Fields{
-		"reason":   reason,
-		"job_id":   job.ID,
-		"box_id":   boxID,
-		"status":   job.Status.Kind,
+		"reason":         reason,
+		"job_id":         job.ID,
+		"correlation_id": job.CorrelationID,
+		"box_id":         boxID,
+		"status":         job.Status.Kind,
 	}).Error("failed job snapshot")
 }
@@ -0,0 +1,266 @@
+package isolate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"flash-go/internal/models"
+)
+
+func TestExecutionTimeout(t *testing.T) {
+	settings := models.ExecutionSettings{WallTimeLimit: 2, MaxWallTimeLimit: 10}
+	tests := []struct {
+		name string
+		job  models.Job
+		want time.Duration
+	}{
+		{"interpreted", models.Job{Settings: settings}, 2 * time.Second},
+		{"compiled", models.Job{Language: models.Language{IsCompiled: true}, Settings: settings}, 12 * time.Second},
+		{"run only", models.Job{Language: models.Language{IsCompiled: true}, Settings: models.ExecutionSettings{WallTimeLimit: 2, MaxWallTimeLimit: 10, RunOnly: true}}, 2 * time.Second},
+		{"several runs", models.Job{Settings: models.ExecutionSettings{WallTimeLimit: 2, NumberOfRuns: 3}}, 6 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := executionTimeout(&tt.job); got != tt.want+execTimeoutMargin {
+			t.Errorf("%s: executionTimeout = %v, want %v", tt.name, got, tt.want+execTimeoutMargin)
+		}
+	}
+}
+
+func TestReserveBoxProbesPastCollisions(t *testing.T) {
+	e := NewExecutor(0, false)
+	first, err := e.reserveBox(boxKey{jobID: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first >= boxIDRange {
+		t.Fatalf("box %d outside the range [0, %d)", first, boxIDRange)
+	}
+	if again, _ := e.reserveBox(boxKey{jobID: 5}); again != first {
+		t.Errorf("reserveBox(5) twice = %d, %d; want the same box", first, again)
+	}
+	second, err := e.reserveBox(boxKey{jobID: 5 + boxIDRange})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first {
+		t.Fatalf("colliding jobs share box %d", first)
+	}
+
+	if boxID, ok := e.detachBox(boxKey{jobID: 5}); !ok || boxID != first {
+		t.Fatalf("detachBox(5) = %d, %v", boxID, ok)
+	}
+	e.releaseBoxID(first)
+	if reused, _ := e.reserveBox(boxKey{jobID: 5 + 2*boxIDRange}); reused != first {
+		t.Errorf("released box %d not reused, got %d", first, reused)
+	}
+}
+
+func TestReserveBoxStressRuns(t *testing.T) {
+	e := NewExecutor(0, false)
+	// Job 6 is a real job whose box a stress run of job 5 must not take.
+	other, err := e.reserveBox(boxKey{jobID: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	boxes := map[uint64]bool{other: true}
+	for run := range 3 {
+		boxID, err := e.reserveBox(boxKey{jobID: 5, run: run})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if boxes[boxID] {
+			t.Fatalf("stress run %d shares box %d", run, boxID)
+		}
+		boxes[boxID] = true
+	}
+	if boxID, ok := e.detachBox(boxKey{jobID: 6}); !ok || boxID != other {
+		t.Errorf("detachBox(6) = %d, %v; want the real job's box %d", boxID, ok, other)
+	}
+}
+
+func TestStressReportsDivergentVerdicts(t *testing.T) {
+	job := models.Job{ID: 9, Settings: models.ExecutionSettings{StressRuns: 4}}
+	// A flaky program: every other run gets a wrong answer.
+	flaky := func(ctx context.Context, run *models.Job, i int) error {
+		if run.ID != job.ID {
+			t.Errorf("run %d has ID %d, want the job's ID %d", i, run.ID, job.ID)
+		}
+		run.Status = models.JobStatus{Kind: models.StatusAccepted}
+		if i%2 == 1 {
+			run.Status = models.JobStatus{Kind: models.StatusWrongAnswer}
+		}
+		run.Output.Stdout = fmt.Sprint(i)
+		run.FinishedAt = int64(i + 1)
+		return nil
+	}
+
+	status, err := stress(context.Background(), &job, flaky)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Kind != models.StatusWrongAnswer || job.Status.Kind != models.StatusWrongAnswer {
+		t.Errorf("status = %s, want the first non-accepted verdict", status.Kind)
+	}
+	if !job.Output.StressDivergent {
+		t.Error("divergent verdicts not reported")
+	}
+	want := []string{models.JobStatus{Kind: models.StatusAccepted}.Description(), models.JobStatus{Kind: models.StatusWrongAnswer}.Description()}
+	if !slices.Equal(job.Output.StressVerdicts, want) {
+		t.Errorf("verdicts = %v, want %v", job.Output.StressVerdicts, want)
+	}
+	if job.Output.Stdout != "0" || job.FinishedAt != 4 {
+		t.Errorf("stdout = %q, finished = %d; want the first run's output and the last finish", job.Output.Stdout, job.FinishedAt)
+	}
+
+	steady := models.Job{ID: 10, Settings: models.ExecutionSettings{StressRuns: 3}}
+	if _, err := stress(context.Background(), &steady, func(ctx context.Context, run *models.Job, i int) error {
+		run.Status = models.JobStatus{Kind: models.StatusAccepted}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if steady.Output.StressDivergent || len(steady.Output.StressVerdicts) != 1 {
+		t.Errorf("steady runs reported verdicts %v", steady.Output.StressVerdicts)
+	}
+}
+
+func TestEnvFlags(t *testing.T) {
+	job := models.Job{Env: map[string]string{"B": "2", "A": "1"}}
+	want := []string{"-E", "PATH=" + sandboxPath, "-E", "HOME=" + sandboxHome, "-E", "A=1", "-E", "B=2"}
+	if got := envFlags(&job); !slices.Equal(got, want) {
+		t.Errorf("envFlags = %v, want %v", got, want)
+	}
+}
+
+func TestInsertCompilerOptions(t *testing.T) {
+	parts := []string{"go", "build", "-o", "main", "main.go"}
+	want := []string{"go", "build", "-o", "main", "-race", "main.go"}
+	if got := insertCompilerOptions(parts, "main.go", []string{"-race"}); !slices.Equal(got, want) {
+		t.Errorf("insertCompilerOptions = %v, want %v", got, want)
+	}
+	parts = []string{"make"}
+	if got := insertCompilerOptions(parts, "main.c", []string{"-j2"}); !slices.Equal(got, []string{"make", "-j2"}) {
+		t.Errorf("insertCompilerOptions without source = %v", got)
+	}
+}
+
+func TestSetupFiles(t *testing.T) {
+	boxPath := t.TempDir()
+	if err := os.Mkdir(filepath.Join(boxPath, "box"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	job := models.Job{
+		SourceCode:      "print(input())",
+		Stdin:           "1 2\n",
+		Language:        models.Language{SourceFile: "main.py"},
+		AdditionalFiles: map[string]string{"helper.py": "x = 1"},
+	}
+	paths, err := setupFiles(&job, boxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"main.py": job.SourceCode, "stdin": "1 2\n", "helper.py": "x = 1"} {
+		got, err := os.ReadFile(filepath.Join(boxPath, "box", name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if paths.StdoutPath != filepath.Join(boxPath, "box", "stdout") {
+		t.Errorf("stdout path = %s", paths.StdoutPath)
+	}
+
+	job.AdditionalFiles = map[string]string{"../escape.py": ""}
+	if _, err := setupFiles(&job, boxPath); !IsPermanent(err) {
+		t.Errorf("escaping file name: err = %v, want a permanent error", err)
+	}
+}
+
+func TestApplyCheckerVerdict(t *testing.T) {
+	tests := []struct {
+		name    string
+		checker models.Job
+		err     error
+		want    string
+	}{
+		{"accepted", models.Job{Status: models.JobStatus{Kind: models.StatusAccepted}}, nil, models.StatusAccepted},
+		{"non-zero exit", models.Job{Status: models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "NZEC"}}, nil, models.StatusWrongAnswer},
+		{"killed", models.Job{Status: models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "SIGSEGV"}}, nil, models.StatusInternalError},
+		{"compile error", models.Job{Status: models.JobStatus{Kind: models.StatusCompilationError}}, nil, models.StatusInternalError},
+		{"execution error", models.Job{}, errors.New("boom"), models.StatusInternalError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.checker.Output.Stderr = " feedback \n"
+			var job models.Job
+			status, err := applyCheckerVerdict(&job, &tt.checker, tt.err)
+			if status.Kind != tt.want {
+				t.Errorf("status = %s, want %s", status.Kind, tt.want)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if tt.want != models.StatusInternalError && job.Output.Message != "feedback" {
+				t.Errorf("message = %q, want the checker's stderr", job.Output.Message)
+			}
+		})
+	}
+}
+
+func TestCheckerJob(t *testing.T) {
+	job := models.Job{ID: 3, Checker: &models.Checker{
+		SourceCode: "check",
+		Language:   models.Language{RunCmd: "python3 main.py"},
+	}}
+	checker := checkerJob(&job, map[string]string{"output": "1"}, "input", "output")
+	if checker.ID == job.ID || checker.ID&^checkerIDBit != job.ID {
+		t.Errorf("checker ID = %d", checker.ID)
+	}
+	if checker.Language.RunCmd != "python3 main.py input output" {
+		t.Errorf("checker run command = %q", checker.Language.RunCmd)
+	}
+	if job.Checker.Language.RunCmd != "python3 main.py" {
+		t.Error("checkerJob changed the job's checker language")
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	err := permanent(errors.New("bad language"))
+	if !IsPermanent(err) || !IsPermanent(errors.Join(errors.New("wrapped"), err)) {
+		t.Error("permanent error not reported as permanent")
+	}
+	if IsPermanent(errors.New("transient")) {
+		t.Error("plain error reported as permanent")
+	}
+}
+
+func TestSetupFilesNormalizesStdin(t *testing.T) {
+	boxPath := t.TempDir()
+	if err := os.Mkdir(filepath.Join(boxPath, "box"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	job := models.Job{
+		Stdin:    "a\r\nb\nc\r\n",
+		Language: models.Language{SourceFile: "main.py"},
+		Settings: models.ExecutionSettings{NormalizeLineEndings: true},
+	}
+	if _, err := setupFiles(&job, boxPath); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(boxPath, "box", "stdin")); string(got) != "a\nb\nc\n" {
+		t.Errorf("stdin = %q, want LF line endings", got)
+	}
+
+	job.Settings.NormalizeLineEndings = false
+	if _, err := setupFiles(&job, boxPath); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(boxPath, "box", "stdin")); string(got) != job.Stdin {
+		t.Errorf("stdin = %q, want it unchanged without normalization", got)
+	}
+}
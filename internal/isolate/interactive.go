@@ -0,0 +1,82 @@
+package isolate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"flash-go/internal/models"
+)
+
+// executeInteractive runs the submission and its interactive checker side by
+// side, each in its own box, with the submission's stdout piped to the
+// checker's stdin and the checker's stdout piped back. The checker's exit
+// code decides the verdict unless the submission itself failed.
+func (e *Executor) executeInteractive(ctx context.Context, job *models.Job) (models.JobStatus, error) {
+	toChecker, fromSubmission, err := os.Pipe()
+	if err != nil {
+		return interactiveFailure(job, err)
+	}
+	toSubmission, fromChecker, err := os.Pipe()
+	if err != nil {
+		toChecker.Close()
+		fromSubmission.Close()
+		return interactiveFailure(job, err)
+	}
+
+	checker := checkerJob(job, map[string]string{models.CheckerInputFile: job.Stdin}, models.CheckerInputFile)
+	submission := *job
+	// Output goes to the checker, so there is nothing to compare.
+	submission.ExpectedOutput = ""
+
+	var (
+		wg                        sync.WaitGroup
+		submissionErr, checkerErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		// Closing our ends once the run is over, or never started, lets the
+		// checker see EOF instead of waiting for its wall time limit.
+		defer toSubmission.Close()
+		defer fromSubmission.Close()
+		_, submissionErr = e.executeWith(ctx, &submission, 0, pipedRun(toSubmission, fromSubmission))
+	}()
+	go func() {
+		defer wg.Done()
+		defer toChecker.Close()
+		defer fromChecker.Close()
+		_, checkerErr = e.executeWith(ctx, &checker, 0, pipedRun(toChecker, fromChecker))
+	}()
+	wg.Wait()
+	e.Cleanup(checker.ID)
+
+	job.Output = submission.Output
+	job.Status = submission.Status
+	job.FinishedAt = max(submission.FinishedAt, checker.FinishedAt)
+	if submissionErr != nil {
+		return job.Status, submissionErr
+	}
+	if job.Status.Kind != models.StatusAccepted {
+		return job.Status, nil
+	}
+	return applyCheckerVerdict(job, &checker, checkerErr)
+}
+
+// pipedRun returns a runFunc that connects the program to stdin and stdout.
+func pipedRun(stdin io.Reader, stdout io.Writer) runFunc {
+	return func(ctx context.Context, job *models.Job, boxID uint64, paths models.JobPaths) error {
+		return runIsolate(ctx, job, boxID, paths, stdin, stdout)
+	}
+}
+
+// interactiveFailure marks job as failed before either program started.
+func interactiveFailure(job *models.Job, err error) (models.JobStatus, error) {
+	job.Status = models.JobStatus{Kind: models.StatusInternalError}
+	job.Output.Message = fmt.Sprintf("interactive setup failed: %v", err)
+	job.FinishedAt = time.Now().UnixNano()
+	return job.Status, err
+}
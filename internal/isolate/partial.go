@@ -0,0 +1,54 @@
+package isolate
+
+import (
+	"time"
+
+	"flash-go/internal/models"
+	"flash-go/internal/utils"
+)
+
+// PartialOutputFunc receives the stdout a running job has produced so far.
+type PartialOutputFunc func(job *models.Job, stdout string)
+
+// EnablePartialOutput makes the executor read a running job's stdout every
+// interval and hand it to flush, so clients can follow long jobs. It must be
+// called before the executor runs any jobs.
+func (e *Executor) EnablePartialOutput(interval time.Duration, flush PartialOutputFunc) {
+	if interval <= 0 || flush == nil {
+		return
+	}
+	e.partialInterval = interval
+	e.partialFlush = flush
+}
+
+// tailStdout flushes stdoutPath for job until the returned stop function is
+// called. Unchanged output is not flushed again.
+func (e *Executor) tailStdout(job *models.Job, stdoutPath string) (stop func()) {
+	if e.partialFlush == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(e.partialInterval)
+		defer ticker.Stop()
+		var last string
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				stdout, _ := utils.ReadFileCapped(stdoutPath, outputLimit)
+				if stdout != last {
+					last = stdout
+					e.partialFlush(job, stdout)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
@@ -0,0 +1,141 @@
+package isolate
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// warmupConcurrency bounds how many boxes are initialized at once by warmup.
+const warmupConcurrency = 8
+
+// warmup initializes boxes up front so the first jobs do not pay for it, then
+// marks the pool ready. Boxes that fail to initialize are retried lazily on
+// acquire.
+func (e *Executor) warmup(boxes []*boxHandle) {
+	start := time.Now()
+	sem := make(chan struct{}, warmupConcurrency)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+	)
+	for _, box := range boxes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(box *boxHandle) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := box.initIfNeeded(context.Background()); err != nil {
+				mu.Lock()
+				failed++
+				mu.Unlock()
+				logrus.WithError(err).WithField("box_id", box.id).Warn("box warmup failed")
+			}
+		}(box)
+	}
+	wg.Wait()
+	e.poolReady.Store(true)
+	logrus.WithFields(logrus.Fields{
+		"boxes":    len(boxes),
+		"failed":   failed,
+		"duration": time.Since(start),
+	}).Info("box pool warmed up")
+}
+
+// PoolReady reports whether the box pool has finished warming up. It is
+// always true when the pool is disabled.
+func (e *Executor) PoolReady() bool {
+	return e.poolReady.Load()
+}
+
+// EnableDynamicPool lets the box pool grow on demand up to maxPool boxes and
+// starts a reaper that cleans up boxes above the base size once they have
+// been idle for idleTimeout. The reaper stops when ctx is cancelled. It must
+// be called before the executor runs any jobs; with maxPool no larger than
+// the base size the pool stays fixed.
+func (e *Executor) EnableDynamicPool(ctx context.Context, maxPool int, idleTimeout time.Duration) {
+	if !e.usePool || maxPool <= e.poolSize {
+		return
+	}
+
+	pool := make(chan *boxHandle, maxPool)
+	for len(e.pool) > 0 {
+		pool <- <-e.pool
+	}
+	e.pool = pool
+	e.maxPool = maxPool
+
+	if idleTimeout > 0 {
+		go e.reapIdleBoxes(ctx, idleTimeout)
+	}
+}
+
+// growPool creates a new box handle if the pool is below its cap, reusing
+// IDs of reaped boxes first. It returns nil when the pool is full.
+func (e *Executor) growPool() *boxHandle {
+	e.poolMu.Lock()
+	defer e.poolMu.Unlock()
+	if e.created >= e.maxPool {
+		return nil
+	}
+	e.created++
+
+	var id uint64
+	if n := len(e.freeIDs); n > 0 {
+		id = e.freeIDs[n-1]
+		e.freeIDs = e.freeIDs[:n-1]
+	} else {
+		id = uint64(e.created)
+	}
+	logrus.WithFields(logrus.Fields{
+		"box_id":    id,
+		"pool_size": e.created,
+	}).Debug("growing box pool")
+	return &boxHandle{id: id}
+}
+
+func (e *Executor) reapIdleBoxes(ctx context.Context, idleTimeout time.Duration) {
+	ticker := time.NewTicker(idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.reapOnce(idleTimeout)
+		}
+	}
+}
+
+// reapOnce cleans up idle boxes until the pool is back at its base size.
+// Boxes currently in use are never touched since they are not in the channel.
+func (e *Executor) reapOnce(idleTimeout time.Duration) {
+	for n := len(e.pool); n > 0; n-- {
+		var box *boxHandle
+		select {
+		case box = <-e.pool:
+		default:
+			return
+		}
+
+		e.poolMu.Lock()
+		reap := e.created > e.poolSize && time.Since(box.lastUsed) > idleTimeout
+		if reap {
+			e.created--
+			e.freeIDs = append(e.freeIDs, box.id)
+		}
+		e.poolMu.Unlock()
+
+		if !reap {
+			e.pool <- box
+			continue
+		}
+		if box.path != "" {
+			cleanupBox(box.id)
+		}
+		logrus.WithField("box_id", box.id).Debug("reaped idle box")
+	}
+}
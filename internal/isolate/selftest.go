@@ -0,0 +1,114 @@
+package isolate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// selfTestBoxID is the box used by the startup self-test. It runs before any
+// job so it cannot collide with a job's box.
+const selfTestBoxID = 0
+
+// CgroupStatus is the outcome of the startup cgroup self-test.
+type CgroupStatus struct {
+	Enabled bool   `json:"enabled"`
+	Version string `json:"version"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+var cgroupStatus atomic.Pointer[CgroupStatus]
+
+// Cgroups returns the result of the last SelfTest, or nil if it has not run.
+func Cgroups() *CgroupStatus {
+	return cgroupStatus.Load()
+}
+
+// SelfTest initialises and cleans up one box with the flags jobs will use, so
+// a broken cgroup setup is reported at startup instead of failing every job.
+func SelfTest(ctx context.Context) CgroupStatus {
+	status := CgroupStatus{Enabled: useCgroup, Version: cgroupVersion(), OK: true}
+	if useCgroup {
+		if err := selfTestBox(ctx); err != nil {
+			status.OK = false
+			status.Error = err.Error()
+		}
+	}
+	cgroupStatus.Store(&status)
+
+	fields := logrus.Fields{
+		"cgroups":        status.Enabled,
+		"cgroup_version": status.Version,
+	}
+	if !status.OK {
+		logrus.WithFields(fields).WithField("error", status.Error).Error(
+			"isolate cgroup self-test failed; every job will end in an internal error. " +
+				"Check that isolate was built with cgroup support, that cg_root in the isolate " +
+				"config points at a delegated cgroup v2 subtree (e.g. run isolate-cg-keeper), " +
+				"and that this process may write to it")
+		return status
+	}
+	logrus.WithFields(fields).Info("isolate self-test passed")
+	return status
+}
+
+func selfTestBox(ctx context.Context) error {
+	id := strconv.Itoa(selfTestBoxID)
+	output, err := exec.CommandContext(ctx, isolatePath, "--cg", "-b", id, "--init").CombinedOutput()
+	if err := checkBinary(err); err != nil {
+		return fmt.Errorf("isolate --cg --init: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+	output, err = exec.CommandContext(ctx, isolatePath, "--cg", "-b", id, "--cleanup").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("isolate --cg --cleanup: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// cgroupVersion reports which cgroup hierarchy is mounted.
+func cgroupVersion() string {
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err == nil {
+		return "v2"
+	}
+	if _, err := os.Stat("/sys/fs/cgroup"); err == nil {
+		return "v1"
+	}
+	return "none"
+}
+
+var (
+	versionOnce sync.Once
+	version     string
+)
+
+// versionTimeout bounds the "isolate --version" call.
+const versionTimeout = 5 * time.Second
+
+// Version returns the isolate version from "isolate --version", or "unknown"
+// if it cannot be determined. The result is cached after the first call.
+func Version() string {
+	versionOnce.Do(func() {
+		version = "unknown"
+		ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+		defer cancel()
+		output, err := exec.CommandContext(ctx, isolatePath, "--version").CombinedOutput()
+		if checkBinary(err) != nil {
+			return
+		}
+		// The first line reads e.g. "The process isolator 2.0".
+		firstLine, _, _ := strings.Cut(string(output), "\n")
+		if fields := strings.Fields(firstLine); len(fields) > 0 {
+			version = fields[len(fields)-1]
+		}
+	})
+	return version
+}
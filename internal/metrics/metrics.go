@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"flash-go/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// JobsEnqueued counts accepted submissions by language.
+	JobsEnqueued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "flash_jobs_enqueued_total",
+		Help: "Jobs enqueued, by language.",
+	}, []string{"language"})
+
+	// JobsCompleted counts finished jobs by language and final status.
+	JobsCompleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "flash_jobs_completed_total",
+		Help: "Jobs that reached a final status, by language and status.",
+	}, []string{"language", "status"})
+
+	// ExecutionDuration observes FinishedAt-StartedAt for finished jobs.
+	ExecutionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "flash_job_execution_seconds",
+		Help:    "Time from job start to finish, by language and status.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"language", "status"})
+
+	// QueueDepth is refreshed from Redis on every scrape.
+	QueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "flash_queue_depth",
+		Help: "Jobs waiting in each queue.",
+	}, []string{"queue"})
+
+	// BoxAcquireWait observes how long executions wait for a pooled box.
+	BoxAcquireWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "flash_box_pool_acquire_wait_seconds",
+		Help:    "Time spent waiting to acquire a box from the pool.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+	})
+
+	// BoxCollisions counts non-pool jobs whose preferred box was held by
+	// another job and had to use the next free one.
+	BoxCollisions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "flash_box_collisions_total",
+		Help: "Non-pool box reservations that probed past a box in use.",
+	})
+)
+
+// ObserveCompletion records a job that reached its final status.
+func ObserveCompletion(job *models.Job) {
+	JobsCompleted.WithLabelValues(job.Language.Name, job.Status.Kind).Inc()
+	if job.StartedAt > 0 && job.FinishedAt >= job.StartedAt {
+		elapsed := time.Duration(job.FinishedAt - job.StartedAt)
+		ExecutionDuration.WithLabelValues(job.Language.Name, job.Status.Kind).Observe(elapsed.Seconds())
+	}
+}
+
+// Handler serves the registered metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
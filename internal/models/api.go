@@ -1,16 +1,62 @@
 package models
 
-
 // CreateJobRequest represents the request body for creating a new job.
 type CreateJobRequest struct {
-	Code        string   `json:"code"`
-	Input       string   `json:"input"`
-	Expected    string   `json:"expected"`
-	Language    string   `json:"language"`
-	TimeLimit   *float64 `json:"time_limit,omitempty"`
-	MemoryLimit *uint64  `json:"memory_limit,omitempty"`
-	StackLimit  *uint64  `json:"stack_limit,omitempty"`
-	Free        bool     `json:"free"`
+	Code               string   `json:"code"`
+	Input              string   `json:"input"`
+	Expected           string   `json:"expected"`
+	Language           string   `json:"language"`
+	LanguageVersion    string   `json:"language_version,omitempty"`
+	TimeLimit          *float64 `json:"time_limit,omitempty"`
+	MemoryLimit        *uint64  `json:"memory_limit,omitempty"`
+	StackLimit         *uint64  `json:"stack_limit,omitempty"`
+	WallTimeLimit      *float64 `json:"wall_time_limit,omitempty"`
+	MaxProcesses       *uint32  `json:"max_processes,omitempty"`
+	Free               bool     `json:"free"`
+	Priority           bool     `json:"priority"`
+	CallbackURL        string   `json:"callback_url,omitempty"`
+	SourceFileOverride string   `json:"source_file_override,omitempty"`
+	CompilerOptions    string   `json:"compiler_options,omitempty"`
+	StressRuns         *uint32  `json:"stress_runs,omitempty"`
+	NumberOfRuns       *uint32  `json:"number_of_runs,omitempty"`
+	FloatTolerance     *float64 `json:"float_tolerance,omitempty"`
+	ComparisonMode     string   `json:"comparison_mode,omitempty"`
+	// AdditionalFiles maps filenames to base64 content written next to the source.
+	AdditionalFiles map[string]string `json:"additional_files,omitempty"`
+	// ArtifactName renames the compiled output for languages that support it.
+	ArtifactName string `json:"artifact_name,omitempty"`
+	// Env sets extra environment variables for compilation and execution.
+	Env map[string]string `json:"env,omitempty"`
+	// ResultTTLSeconds sets how long the result is kept; it is clamped to
+	// the server maximum.
+	ResultTTLSeconds *int64 `json:"result_ttl_seconds,omitempty"`
+	// Checker judges the submission with a problem-supplied program.
+	Checker *CheckerRequest `json:"checker,omitempty"`
+	// StrictMode compiles with warnings treated as errors (C++ only).
+	StrictMode bool `json:"strict_mode,omitempty"`
+	// NormalizeLineEndings treats CRLF and LF line endings as equal.
+	NormalizeLineEndings bool `json:"normalize_line_endings,omitempty"`
+	// ReportPresentationError distinguishes whitespace-only mismatches from
+	// wrong answers. The resulting status has no Judge0 equivalent.
+	ReportPresentationError bool `json:"report_presentation_error,omitempty"`
+	// InputMode is "stdin" (default) or "file" to pass the input as a file
+	// named on the command line.
+	InputMode string `json:"input_mode,omitempty"`
+	// CompileOnly compiles the code without running it.
+	CompileOnly bool `json:"compile_only,omitempty"`
+	// RunOnly runs Binary, a base64 precompiled program, instead of
+	// compiling Code.
+	RunOnly bool   `json:"run_only,omitempty"`
+	Binary  string `json:"binary,omitempty"`
+	// DiskQuotaKB caps the total size of files the program may write.
+	DiskQuotaKB *uint64 `json:"disk_quota_kb,omitempty"`
+}
+
+// CheckerRequest describes a checker program in a create request.
+type CheckerRequest struct {
+	Code        string `json:"code"`
+	Language    string `json:"language"`
+	Interactive bool   `json:"interactive"`
 }
 
 // CreateJobResponse represents the response after creating a job.
@@ -27,17 +73,42 @@ type CheckStatus struct {
 
 // CheckResponse represents the response when checking a job status.
 type CheckResponse struct {
-	CreatedAt     int64       `json:"created_at"`
-	StartedAt     int64       `json:"started_at"`
-	FinishedAt    int64       `json:"finished_at"`
-	Stdout        string      `json:"stdout"`
-	Time          float64     `json:"time"`
-	Memory        uint64      `json:"memory"`
-	Stderr        string      `json:"stderr"`
-	Token         uint64      `json:"token"`
-	CompileOutput string      `json:"compile_output"`
-	Message       string      `json:"message"`
-	Status        CheckStatus `json:"status"`
+	CreatedAt       int64       `json:"created_at"`
+	StartedAt       int64       `json:"started_at"`
+	FinishedAt      int64       `json:"finished_at"`
+	Stdout          string      `json:"stdout"`
+	Time            *float64    `json:"time"`
+	Memory          uint64      `json:"memory"`
+	CgMem           uint64      `json:"cg_mem,omitempty"`
+	MaxRSS          uint64      `json:"max_rss,omitempty"`
+	Stderr          string      `json:"stderr"`
+	Token           uint64      `json:"token"`
+	CompileOutput   string      `json:"compile_output"`
+	Message         string      `json:"message"`
+	Truncated       bool        `json:"truncated,omitempty"`
+	StdoutTruncated bool        `json:"stdout_truncated,omitempty"`
+	StderrTruncated bool        `json:"stderr_truncated,omitempty"`
+	IsCompiled      bool        `json:"is_compiled"`
+	StressVerdicts  []string    `json:"stress_verdicts,omitempty"`
+	StressDivergent bool        `json:"stress_divergent,omitempty"`
+	Diff            *OutputDiff `json:"diff,omitempty"`
+	SourceHash      string      `json:"source_hash,omitempty"`
+	Attempts        int         `json:"attempts,omitempty"`
+	ExitCode        *int        `json:"exit_code"`
+	Signal          string      `json:"signal,omitempty"`
+	// QueueTimeMs is how long the job waited before a worker picked it up.
+	QueueTimeMs *int64 `json:"queue_time_ms,omitempty"`
+	// WallTimeMs is how long the worker spent on the job.
+	WallTimeMs *int64      `json:"wall_time_ms,omitempty"`
+	Status     CheckStatus `json:"status"`
+	// Debug is only included when the server enables debug details.
+	Debug *JobDebug `json:"debug,omitempty"`
+}
+
+// JobDebug identifies where a job was executed.
+type JobDebug struct {
+	BoxID    uint64 `json:"box_id"`
+	WorkerID int    `json:"worker_id"`
 }
 
 // Judge0Status represents a Judge0-compatible status.
@@ -55,17 +126,26 @@ type Judge0Submission struct {
 	CPUTimeLimit             float64 `json:"cpu_time_limit,omitempty"`
 	MemoryLimit              int     `json:"memory_limit,omitempty"`
 	MaxProcessesAndOrThreads int     `json:"max_processes_and_or_threads,omitempty"`
+	CompilerOptions          string  `json:"compiler_options,omitempty"`
+	// TrimWhitespace selects trailing_trim (true) or exact (false) comparison.
+	TrimWhitespace *bool `json:"trim_whitespace,omitempty"`
+	// ComparisonMode selects a comparison mode directly and wins over TrimWhitespace.
+	ComparisonMode string `json:"comparison_mode,omitempty"`
+	NumberOfRuns   uint32 `json:"number_of_runs,omitempty"`
 }
 
 // Judge0BatchSubmissionRequest represents a batch submission request.
 type Judge0BatchSubmissionRequest struct {
 	Submissions []Judge0Submission `json:"submissions"`
 	Free        bool               `json:"free"`
+	Priority    bool               `json:"priority"`
 }
 
 // Judge0SubmissionResponse represents the response for a single submission.
 type Judge0SubmissionResponse struct {
-	Token string `json:"token"`
+	Token string `json:"token,omitempty"`
+	// Error explains why a batch entry was not enqueued.
+	Error string `json:"error,omitempty"`
 }
 
 // Judge0SubmissionDetails represents detailed information about a submission.
@@ -81,9 +161,47 @@ type Judge0SubmissionDetails struct {
 	Message       *string      `json:"message,omitempty"`
 	Time          *string      `json:"time,omitempty"`
 	Memory        *int         `json:"memory,omitempty"`
+	Truncated     bool         `json:"truncated,omitempty"`
+	IsCompiled    bool         `json:"is_compiled"`
+	SourceHash    string       `json:"source_hash,omitempty"`
+	ExitCode      *int         `json:"exit_code,omitempty"`
+	ExitSignal    *int         `json:"exit_signal,omitempty"`
+	Signal        string       `json:"signal,omitempty"`
 }
 
 // Judge0BatchResponse represents the response for a batch query.
 type Judge0BatchResponse struct {
 	Submissions []*Judge0SubmissionDetails `json:"submissions"`
 }
+
+// LanguageInfo describes a supported language in the languages listing.
+type LanguageInfo struct {
+	ID         int    `json:"id,omitempty"`
+	Name       string `json:"name"`
+	SourceFile string `json:"source_file"`
+	IsCompiled bool   `json:"is_compiled"`
+	Judge0IDs  []int  `json:"judge0_ids,omitempty"`
+	// Versions lists the language_version values accepted besides the default.
+	Versions []string `json:"versions,omitempty"`
+}
+
+// BatchFailure identifies the first non-accepted submission in a batch.
+type BatchFailure struct {
+	Token  string       `json:"token"`
+	Status Judge0Status `json:"status"`
+}
+
+// BatchSummary aggregates the results of a batch of submissions.
+type BatchSummary struct {
+	Total        int           `json:"total"`
+	Finished     int           `json:"finished"`
+	Pending      int           `json:"pending"`
+	Missing      int           `json:"missing"`
+	Accepted     int           `json:"accepted"`
+	Done         bool          `json:"done"`
+	FirstFailure *BatchFailure `json:"first_failure,omitempty"`
+	TotalTime    float64       `json:"total_time"`
+	MaxMemory    uint64        `json:"max_memory"`
+	// StatusCounts counts found submissions by status description.
+	StatusCounts map[string]int `json:"status_counts"`
+}
@@ -1,24 +1,79 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Status constants for job processing.
 const (
-	StatusQueued            = "Queued"
-	StatusProcessing        = "Processing"
-	StatusAccepted          = "Accepted"
-	StatusWrongAnswer       = "WrongAnswer"
-	StatusTimeLimitExceeded = "TimeLimitExceeded"
-	StatusCompilationError  = "CompilationError"
-	StatusRuntimeError      = "RuntimeError"
-	StatusInternalError     = "InternalError"
-	StatusExecFormatError   = "ExecFormatError"
+	StatusQueued              = "Queued"
+	StatusProcessing          = "Processing"
+	StatusAccepted            = "Accepted"
+	StatusWrongAnswer         = "WrongAnswer"
+	StatusTimeLimitExceeded   = "TimeLimitExceeded"
+	StatusCompilationError    = "CompilationError"
+	StatusRuntimeError        = "RuntimeError"
+	StatusInternalError       = "InternalError"
+	StatusExecFormatError     = "ExecFormatError"
+	StatusCancelled           = "Cancelled"
+	StatusOutputLimitExceeded = "OutputLimitExceeded"
+	// StatusCustom is a deployment-defined verdict mapped from an exit code.
+	StatusCustom = "Custom"
+	// StatusMemoryLimitExceeded is reported when the program was killed for
+	// exceeding its memory limit.
+	StatusMemoryLimitExceeded = "MemoryLimitExceeded"
+	// StatusPresentationError is reported when the output has the expected
+	// tokens but differs in whitespace under the job's comparison mode. It is
+	// only used when ExecutionSettings.ReportPresentationError is set;
+	// otherwise such output is a wrong answer.
+	StatusPresentationError = "PresentationError"
 )
 
+// Output comparison modes for ExecutionSettings.ComparisonMode.
+const (
+	// ComparisonExact requires stdout to equal the expected output byte for byte.
+	ComparisonExact = "exact"
+	// ComparisonTrailingTrim ignores leading and trailing whitespace. It is the default.
+	ComparisonTrailingTrim = "trailing_trim"
+	// ComparisonIgnoreAllWhitespace ignores every whitespace character.
+	ComparisonIgnoreAllWhitespace = "ignore_all_whitespace"
+	// ComparisonToken compares whitespace-separated token sequences.
+	ComparisonToken = "token"
+	// ComparisonTrailingNewline ignores a single trailing newline ("\n" or
+	// "\r\n") and compares the rest exactly.
+	ComparisonTrailingNewline = "trailing_newline"
+	// ComparisonFloatTolerance compares whitespace-separated tokens, treating
+	// numeric tokens as equal within ExecutionSettings.FloatTolerance.
+	ComparisonFloatTolerance = "float_tolerance"
+)
+
+// DefaultFloatTolerance is the absolute and relative epsilon used by
+// ComparisonFloatTolerance when the job does not set one.
+const DefaultFloatTolerance = 1e-6
+
+// Input modes for ExecutionSettings.InputMode.
+const (
+	// InputModeStdin pipes the input to the program's stdin. It is the default.
+	InputModeStdin = "stdin"
+	// InputModeFile writes the input to InputFile in the box and passes its
+	// name as the program's last argument, for programs that open the file
+	// named in argv[1] (sys.argv[1] in Python, args[0] in Java). Every
+	// built-in language forwards trailing arguments to the program.
+	InputModeFile = "file"
+)
+
+// InputFile is the box file holding the input in InputModeFile.
+const InputFile = "input.txt"
+
 // JobStatus represents the current state of a job.
 type JobStatus struct {
 	Kind        string `json:"kind"`
 	RuntimeCode string `json:"runtime_code,omitempty"`
+	// Signal is the number of the signal that killed the program, if any.
+	Signal int `json:"signal,omitempty"`
+	// Label is the description of a StatusCustom verdict.
+	Label string `json:"label,omitempty"`
 }
 
 // ID returns the Judge0-style status ID used by the API.
@@ -55,11 +110,57 @@ func (s JobStatus) ID() int {
 		return 13
 	case StatusExecFormatError:
 		return 14
+	case StatusCancelled:
+		return 15
+	case StatusOutputLimitExceeded:
+		return 16
+	case StatusCustom:
+		return 17
+	case StatusMemoryLimitExceeded:
+		return 18
+	case StatusPresentationError:
+		return 19
 	default:
 		return 13
 	}
 }
 
+// IsTerminal reports whether the job has finished and will not change further.
+func (s JobStatus) IsTerminal() bool {
+	switch s.Kind {
+	case StatusQueued, StatusProcessing:
+		return false
+	default:
+		return true
+	}
+}
+
+// CanTransition reports whether a job may move from status kind from to to.
+// Queued and processing jobs may move anywhere. A terminal status is final,
+// except that an internal error may be retried (Processing) or requeued.
+func CanTransition(from, to string) bool {
+	switch from {
+	case "", to, StatusQueued, StatusProcessing:
+		return true
+	case StatusInternalError:
+		return to == StatusProcessing || to == StatusQueued
+	default:
+		return false
+	}
+}
+
+// SignalName returns the name of the signal that killed the program, or ""
+// if it was not killed by a signal.
+func (s JobStatus) SignalName() string {
+	if s.Signal > 0 {
+		return SignalName(s.Signal)
+	}
+	if strings.HasPrefix(s.RuntimeCode, "SIG") {
+		return s.RuntimeCode
+	}
+	return ""
+}
+
 // Description returns the human-readable status string used by the API.
 func (s JobStatus) Description() string {
 	switch s.Kind {
@@ -79,11 +180,27 @@ func (s JobStatus) Description() string {
 		if s.RuntimeCode == "" {
 			return "Runtime Error"
 		}
+		if s.Signal > 0 {
+			if name := SignalName(s.Signal); name != "" {
+				return fmt.Sprintf("Runtime Error: %s (signal %d)", name, s.Signal)
+			}
+			return fmt.Sprintf("Runtime Error: signal %d", s.Signal)
+		}
 		return fmt.Sprintf("Runtime Error: (%s)", s.RuntimeCode)
 	case StatusInternalError:
 		return "Internal Error"
 	case StatusExecFormatError:
 		return "Exec Format Error"
+	case StatusCancelled:
+		return "Cancelled"
+	case StatusOutputLimitExceeded:
+		return "Output Limit Exceeded"
+	case StatusCustom:
+		return s.Label
+	case StatusMemoryLimitExceeded:
+		return "Memory Limit Exceeded"
+	case StatusPresentationError:
+		return "Presentation Error"
 	default:
 		return "Internal Error"
 	}
@@ -95,9 +212,33 @@ type JobOutput struct {
 	Stderr        string  `json:"stderr"`
 	CompileOutput string  `json:"compile_output"`
 	Time          float64 `json:"time"`
+	TimeAvailable bool    `json:"time_available"`
 	Memory        uint64  `json:"memory"`
-	ExitCode      int     `json:"exit_code"`
-	Message       string  `json:"message"`
+	// CgMem and MaxRSS are the cgroup-wide and single-process peaks that
+	// Memory combines.
+	CgMem     uint64 `json:"cg_mem,omitempty"`
+	MaxRSS    uint64 `json:"max_rss,omitempty"`
+	ExitCode  int    `json:"exit_code"`
+	Message   string `json:"message"`
+	Truncated bool   `json:"truncated,omitempty"`
+	// StdoutTruncated and StderrTruncated tell which stream was truncated.
+	StdoutTruncated bool `json:"stdout_truncated,omitempty"`
+	StderrTruncated bool `json:"stderr_truncated,omitempty"`
+	// StressVerdicts lists the distinct verdicts seen across stress runs.
+	StressVerdicts  []string `json:"stress_verdicts,omitempty"`
+	StressDivergent bool     `json:"stress_divergent,omitempty"`
+	// Diff locates the first mismatch for wrong answers and presentation errors.
+	Diff *OutputDiff `json:"diff,omitempty"`
+	// BoxID and WorkerID record where the job ran, for debugging.
+	BoxID    uint64 `json:"box_id,omitempty"`
+	WorkerID int    `json:"worker_id,omitempty"`
+}
+
+// OutputDiff describes the first line where stdout differs from the expected output.
+type OutputDiff struct {
+	Line     int    `json:"line"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
 }
 
 // Language describes how to compile and run a job.
@@ -107,41 +248,141 @@ type Language struct {
 	CompileCmd string `json:"compile_cmd"`
 	RunCmd     string `json:"run_cmd"`
 	IsCompiled bool   `json:"is_compiled"`
+	// Artifact names the compiled output. Commands refer to it through
+	// ArtifactPlaceholder so it can be renamed per submission.
+	Artifact string `json:"artifact,omitempty"`
+	// Version identifies a non-default toolchain for the language, such as
+	// "3.12" for python or "clang" for cpp. Empty for the default.
+	Version string `json:"version,omitempty"`
+	// DefaultSettings overrides the global defaults for this language when set.
+	DefaultSettings *ExecutionSettings `json:"default_settings,omitempty"`
+}
+
+// ArtifactPlaceholder is replaced by Language.Artifact in compile and run commands.
+const ArtifactPlaceholder = "{artifact}"
+
+// CompileCommand returns the compile command with the artifact name filled in.
+func (l Language) CompileCommand() string {
+	return strings.ReplaceAll(l.CompileCmd, ArtifactPlaceholder, l.Artifact)
+}
+
+// RunCommand returns the run command with the artifact name filled in.
+func (l Language) RunCommand() string {
+	return strings.ReplaceAll(l.RunCmd, ArtifactPlaceholder, l.Artifact)
 }
 
 // ExecutionSettings defines resource limits for a job.
 type ExecutionSettings struct {
-	MaxCPUTimeLimit float64 `json:"max_cpu_time_limit"`
-	CPUTimeLimit  float64 `json:"cpu_time_limit"`
-	WallTimeLimit float64 `json:"wall_time_limit"`
-	MaxWallTimeLimit float64 `json:"max_wall_time_limit"`
-	MemoryLimit   uint64  `json:"memory_limit"`
-	MaxMemoryLimit uint64  `json:"max_memory_limit"`
-	MaxStackLimit uint64  `json:"max_stack_limit"`
-	StackLimit    uint64  `json:"stack_limit"`
-	MaxProcesses  uint32  `json:"max_processes"`
-	MaxFileSize   uint64  `json:"max_file_size"`
-	EnableNetwork bool    `json:"enable_network"`
-	EnablePerProcessAndThreadTimeLimit    bool    `json:"enable_per_process_and_thread_time_limit,omitempty"`
-	EnablePerProcessAndThreadMemoryLimit  bool    `json:"enable_per_process_and_thread_memory_limit,omitempty"`
-	RedirectStderrToStdout                bool    `json:"redirect_stderr_to_stdout,omitempty"`
+	MaxCPUTimeLimit                      float64 `json:"max_cpu_time_limit"`
+	CPUTimeLimit                         float64 `json:"cpu_time_limit"`
+	WallTimeLimit                        float64 `json:"wall_time_limit"`
+	MaxWallTimeLimit                     float64 `json:"max_wall_time_limit"`
+	MemoryLimit                          uint64  `json:"memory_limit"`
+	MaxMemoryLimit                       uint64  `json:"max_memory_limit"`
+	MaxStackLimit                        uint64  `json:"max_stack_limit"`
+	StackLimit                           uint64  `json:"stack_limit"`
+	MaxProcesses                         uint32  `json:"max_processes"`
+	MaxFileSize                          uint64  `json:"max_file_size"`
+	EnableNetwork                        bool    `json:"enable_network"`
+	EnablePerProcessAndThreadTimeLimit   bool    `json:"enable_per_process_and_thread_time_limit,omitempty"`
+	EnablePerProcessAndThreadMemoryLimit bool    `json:"enable_per_process_and_thread_memory_limit,omitempty"`
+	RedirectStderrToStdout               bool    `json:"redirect_stderr_to_stdout,omitempty"`
+	// StressRuns, when above one, runs the program that many times concurrently.
+	StressRuns uint32 `json:"stress_runs,omitempty"`
+	// NumberOfRuns, when above one, runs the compiled program that many times
+	// in a row. The first run decides the output and verdict; time and memory
+	// are averaged across runs.
+	NumberOfRuns uint32 `json:"number_of_runs,omitempty"`
+	// ComparisonMode selects how stdout is checked against the expected
+	// output; empty means ComparisonTrailingTrim.
+	ComparisonMode string `json:"comparison_mode,omitempty"`
+	// FloatTolerance is the epsilon for ComparisonFloatTolerance; 0 means
+	// DefaultFloatTolerance.
+	FloatTolerance float64 `json:"float_tolerance,omitempty"`
+	// MaxOutputBytes caps combined stdout, stderr and compile output; 0 disables it.
+	MaxOutputBytes uint64 `json:"max_output_bytes,omitempty"`
+	// MaxStdoutBytes and MaxStderrBytes cap how much of each stream is kept;
+	// longer output is truncated. 0 falls back to the executor default.
+	MaxStdoutBytes int64 `json:"max_stdout_bytes,omitempty"`
+	MaxStderrBytes int64 `json:"max_stderr_bytes,omitempty"`
+	// NormalizeLineEndings converts CRLF to LF in stdin and in the output and
+	// expected output before they are compared.
+	NormalizeLineEndings bool `json:"normalize_line_endings,omitempty"`
+	// ReportPresentationError reports StatusPresentationError instead of
+	// StatusWrongAnswer when the output fails the comparison but has the
+	// expected tokens.
+	ReportPresentationError bool `json:"report_presentation_error,omitempty"`
+	// InputMode selects how the input reaches the program; empty means
+	// InputModeStdin.
+	InputMode string `json:"input_mode,omitempty"`
+	// CompileOnly stops after the compile step and reports Accepted or
+	// CompilationError without running the program.
+	CompileOnly bool `json:"compile_only,omitempty"`
+	// RunOnly skips the compile step and runs Job.Binary as the language's
+	// compiled artifact.
+	RunOnly bool `json:"run_only,omitempty"`
+	// DiskQuotaKB caps the total size of files in the box; 0 means the
+	// executor default. Pool boxes always use the default.
+	DiskQuotaKB uint64 `json:"disk_quota_kb,omitempty"`
 }
 
 // Job represents a unit of work in the judge.
 type Job struct {
-	ID             uint64            `json:"id"`
-	SourceCode     string            `json:"source_code"`
-	Language       Language          `json:"language"`
-	Stdin          string            `json:"stdin"`
-	ExpectedOutput string            `json:"expected_output"`
-	Settings       ExecutionSettings `json:"settings"`
-	Status         JobStatus         `json:"status"`
-	CreatedAt      int64             `json:"created_at"`
-	StartedAt      int64             `json:"started_at"`
-	FinishedAt     int64             `json:"finished_at"`
-	Output         JobOutput         `json:"output"`
+	ID              uint64            `json:"id"`
+	SourceCode      string            `json:"source_code"`
+	Language        Language          `json:"language"`
+	Stdin           string            `json:"stdin"`
+	ExpectedOutput  string            `json:"expected_output"`
+	Settings        ExecutionSettings `json:"settings"`
+	Status          JobStatus         `json:"status"`
+	CreatedAt       int64             `json:"created_at"`
+	StartedAt       int64             `json:"started_at"`
+	FinishedAt      int64             `json:"finished_at"`
+	Output          JobOutput         `json:"output"`
+	CallbackURL     string            `json:"callback_url,omitempty"`
+	Queue           string            `json:"queue,omitempty"`
+	Priority        bool              `json:"priority,omitempty"`
+	CompilerOptions string            `json:"compiler_options,omitempty"`
+	SourceHash      string            `json:"source_hash,omitempty"`
+	// Attempts counts execution attempts, including retries after errors.
+	Attempts int `json:"attempts,omitempty"`
+	// AdditionalFiles holds extra files (name to content) written into the box.
+	AdditionalFiles map[string]string `json:"additional_files,omitempty"`
+	// Env holds extra environment variables passed into the sandbox.
+	Env map[string]string `json:"env,omitempty"`
+	// ResultTTL, in seconds, overrides how long the job is kept in Redis.
+	ResultTTL int64 `json:"result_ttl,omitempty"`
+	// Checker, when set, judges the submission instead of comparing output.
+	Checker *Checker `json:"checker,omitempty"`
+	// CorrelationID ties the job's log lines to the request that created it.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// InFlightKey is the client whose in-flight slot the job holds; the
+	// worker releases it when the job finishes.
+	InFlightKey string `json:"in_flight_key,omitempty"`
+	// Binary is the precompiled program for run-only jobs.
+	Binary []byte `json:"binary,omitempty"`
 }
 
+// Checker is a problem-supplied program that judges a submission. Exit code 0
+// accepts it and a non-zero exit code rejects it; its stderr is reported as
+// feedback. A standard checker runs after the submission and is called with
+// CheckerInputFile, CheckerOutputFile and CheckerExpectedFile as arguments.
+type Checker struct {
+	SourceCode string            `json:"source_code"`
+	Language   Language          `json:"language"`
+	Settings   ExecutionSettings `json:"settings"`
+	// Interactive checkers talk to the running submission over its stdin and
+	// stdout and read the test input from CheckerInputFile.
+	Interactive bool `json:"interactive,omitempty"`
+}
+
+// Files written into a checker's box.
+const (
+	CheckerInputFile    = "input.txt"
+	CheckerOutputFile   = "output.txt"
+	CheckerExpectedFile = "expected.txt"
+)
+
 // JobPaths holds file paths for a job execution sandbox.
 type JobPaths struct {
 	BoxPath           string
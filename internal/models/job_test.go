@@ -0,0 +1,85 @@
+package models
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"", StatusQueued, true},
+		{StatusQueued, StatusProcessing, true},
+		{StatusQueued, StatusCancelled, true},
+		{StatusProcessing, StatusAccepted, true},
+		{StatusProcessing, StatusQueued, true},
+		{StatusAccepted, StatusAccepted, true},
+		{StatusAccepted, StatusProcessing, false},
+		{StatusAccepted, StatusQueued, false},
+		{StatusWrongAnswer, StatusAccepted, false},
+		{StatusCancelled, StatusProcessing, false},
+		{StatusInternalError, StatusProcessing, true},
+		{StatusInternalError, StatusQueued, true},
+		{StatusInternalError, StatusAccepted, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestRuntimeErrorDescription(t *testing.T) {
+	tests := []struct {
+		status JobStatus
+		want   string
+	}{
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "SIGSEGV", Signal: 11}, "Runtime Error: SIGSEGV (signal 11)"},
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "Other", Signal: 9}, "Runtime Error: SIGKILL (signal 9)"},
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "Other", Signal: 31}, "Runtime Error: SIGSYS (signal 31)"},
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "Other", Signal: 36}, "Runtime Error: SIGRTMIN+2 (signal 36)"},
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "Other", Signal: 99}, "Runtime Error: signal 99"},
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "NZEC"}, "Runtime Error: (NZEC)"},
+	}
+	for _, tt := range tests {
+		if got := tt.status.Description(); got != tt.want {
+			t.Errorf("Description(%+v) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestSignalName(t *testing.T) {
+	tests := []struct {
+		status JobStatus
+		want   string
+	}{
+		{JobStatus{Kind: StatusRuntimeError, Signal: 8}, "SIGFPE"},
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "SIGABRT"}, "SIGABRT"},
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "NZEC"}, ""},
+		{JobStatus{Kind: StatusAccepted}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.status.SignalName(); got != tt.want {
+			t.Errorf("SignalName(%+v) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestStatusID(t *testing.T) {
+	tests := []struct {
+		status JobStatus
+		want   int
+	}{
+		{JobStatus{Kind: StatusAccepted}, 3},
+		{JobStatus{Kind: StatusWrongAnswer}, 4},
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "SIGSEGV"}, 7},
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "NZEC"}, 11},
+		{JobStatus{Kind: StatusRuntimeError, RuntimeCode: "Other"}, 12},
+		{JobStatus{Kind: StatusPresentationError}, 19},
+		{JobStatus{Kind: "unknown"}, 13},
+	}
+	for _, tt := range tests {
+		if got := tt.status.ID(); got != tt.want {
+			t.Errorf("ID(%+v) = %d, want %d", tt.status, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import "strconv"
+
+// signalNames maps Linux signal numbers to their names.
+var signalNames = map[int]string{
+	1:  "SIGHUP",
+	2:  "SIGINT",
+	3:  "SIGQUIT",
+	4:  "SIGILL",
+	5:  "SIGTRAP",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	9:  "SIGKILL",
+	10: "SIGUSR1",
+	11: "SIGSEGV",
+	12: "SIGUSR2",
+	13: "SIGPIPE",
+	14: "SIGALRM",
+	15: "SIGTERM",
+	16: "SIGSTKFLT",
+	17: "SIGCHLD",
+	18: "SIGCONT",
+	19: "SIGSTOP",
+	20: "SIGTSTP",
+	21: "SIGTTIN",
+	22: "SIGTTOU",
+	23: "SIGURG",
+	24: "SIGXCPU",
+	25: "SIGXFSZ",
+	26: "SIGVTALRM",
+	27: "SIGPROF",
+	28: "SIGWINCH",
+	29: "SIGIO",
+	30: "SIGPWR",
+	31: "SIGSYS",
+}
+
+// SignalName returns the name of a Linux signal number, e.g. "SIGSEGV" for 11.
+// Real-time signals are named SIGRTMIN+n; unknown numbers yield "".
+func SignalName(sig int) string {
+	if name, ok := signalNames[sig]; ok {
+		return name
+	}
+	if sig >= 34 && sig <= 64 {
+		return "SIGRTMIN+" + strconv.Itoa(sig-34)
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultEnqueueBatchSize = 128
+
+// errBatcherStopped is returned by submit once the flusher has exited.
+var errBatcherStopped = errors.New("enqueue batcher stopped")
+
+// enqueueRequest is a single job waiting to be written by the batcher.
+type enqueueRequest struct {
+	jobID   uint64
+	key     string
+	queue   string
+	payload []byte
+	ttl     time.Duration
+	done    chan error
+}
+
+// enqueueBatcher coalesces enqueue requests arriving within a short window
+// into a single Redis pipeline of SET+RPUSH commands.
+type enqueueBatcher struct {
+	client   *Client
+	requests chan enqueueRequest
+	window   time.Duration
+	maxBatch int
+	stopped  chan struct{}
+}
+
+// EnableEnqueueBatching routes job creation through a background flusher that
+// writes all jobs submitted within window in one pipeline. Batching stops when
+// ctx is cancelled; later enqueues fall back to a direct pipeline.
+func (c *Client) EnableEnqueueBatching(ctx context.Context, window time.Duration, maxBatch int) {
+	if window <= 0 {
+		return
+	}
+	if maxBatch < 1 {
+		maxBatch = defaultEnqueueBatchSize
+	}
+	b := &enqueueBatcher{
+		client:   c,
+		requests: make(chan enqueueRequest, maxBatch*4),
+		window:   window,
+		maxBatch: maxBatch,
+		stopped:  make(chan struct{}),
+	}
+	c.batcher = b
+	go b.run(ctx)
+}
+
+// submit hands a request to the flusher and waits for its pipeline result.
+// ctx only bounds the hand-off: once the flusher has the job it may already
+// be written, so the result is awaited even if ctx is cancelled.
+func (b *enqueueBatcher) submit(ctx context.Context, req enqueueRequest) error {
+	req.done = make(chan error, 1)
+	select {
+	case b.requests <- req:
+	case <-b.stopped:
+		return errBatcherStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-req.done:
+		return err
+	case <-b.stopped:
+		// The final drain reports results before stopped is closed.
+		select {
+		case err := <-req.done:
+			return err
+		default:
+			return errBatcherStopped
+		}
+	}
+}
+
+func (b *enqueueBatcher) run(ctx context.Context) {
+	batch := make([]enqueueRequest, 0, b.maxBatch)
+	for {
+		select {
+		case <-ctx.Done():
+			b.drain(batch)
+			close(b.stopped)
+			return
+		case req := <-b.requests:
+			batch = append(batch, req)
+		}
+
+		timer := time.NewTimer(b.window)
+	collect:
+		for len(batch) < b.maxBatch {
+			select {
+			case req := <-b.requests:
+				batch = append(batch, req)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		b.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+// drain flushes anything still buffered when the batcher stops.
+func (b *enqueueBatcher) drain(batch []enqueueRequest) {
+	for {
+		select {
+		case req := <-b.requests:
+			batch = append(batch, req)
+		default:
+			if len(batch) > 0 {
+				b.flush(batch)
+			}
+			return
+		}
+	}
+}
+
+func (b *enqueueBatcher) flush(batch []enqueueRequest) {
+	ctx := context.Background()
+	pipe := b.client.rdb.TxPipeline()
+	for _, req := range batch {
+		pipe.Set(ctx, req.key, req.payload, req.ttl)
+		pipe.RPush(ctx, req.queue, strconv.FormatUint(req.jobID, 10))
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		logrus.WithError(err).WithField("batch_size", len(batch)).Error("failed to execute Redis pipeline in enqueue batch")
+	}
+	for _, req := range batch {
+		req.done <- err
+	}
+}
@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEnqueueBatching(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.EnableEnqueueBatching(ctx, 20*time.Millisecond, 8)
+
+	const jobs = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, jobs)
+	for id := uint64(1); id <= jobs; id++ {
+		wg.Add(1)
+		go func(id uint64) {
+			defer wg.Done()
+			errs <- c.Enqueue(context.Background(), queuedJob(id), QueueMain)
+		}(id)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids, err := c.PeekQueue(context.Background(), QueueMain, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != jobs {
+		t.Fatalf("queue holds %d jobs, want %d", len(ids), jobs)
+	}
+	seen := make(map[uint64]bool, jobs)
+	for _, id := range ids {
+		if seen[id] {
+			t.Errorf("job %d queued twice", id)
+		}
+		seen[id] = true
+		job, err := c.GetJob(context.Background(), id)
+		if err != nil || job == nil || job.ID != id || job.Queue != string(QueueMain) {
+			t.Errorf("job %d stored as %+v, %v", id, job, err)
+		}
+	}
+}
+
+func TestEnqueueBatchingIgnoresCancelAfterHandOff(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.EnableEnqueueBatching(ctx, 50*time.Millisecond, 8)
+
+	// The caller gives up while the job sits in the batch window; the
+	// result must still reflect that the job was written.
+	reqCtx, reqCancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer reqCancel()
+	if err := c.Enqueue(reqCtx, queuedJob(1), QueueMain); err != nil {
+		t.Fatalf("Enqueue = %v after hand-off, want nil", err)
+	}
+	if n, _ := c.QueueLength(context.Background(), QueueMain); n != 1 {
+		t.Errorf("queue length = %d, want 1", n)
+	}
+}
+
+func TestEnqueueBatchingAfterStop(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	c.EnableEnqueueBatching(ctx, time.Millisecond, 8)
+	cancel()
+	<-c.batcher.stopped
+
+	// Once the flusher is gone enqueues fall back to a direct pipeline.
+	if err := c.Enqueue(context.Background(), queuedJob(1), QueueMain); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.QueueLength(context.Background(), QueueMain); n != 1 {
+		t.Errorf("queue length = %d, want 1", n)
+	}
+}
+
+func BenchmarkEnqueue(b *testing.B) {
+	for _, window := range []time.Duration{0, time.Millisecond} {
+		b.Run(fmt.Sprintf("window=%s", window), func(b *testing.B) {
+			c, _ := newTestClient(b)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			c.EnableEnqueueBatching(ctx, window, 0)
+
+			var next uint64
+			var mu sync.Mutex
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					mu.Lock()
+					next++
+					id := next
+					mu.Unlock()
+					if err := c.Enqueue(context.Background(), queuedJob(id), QueueMain); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
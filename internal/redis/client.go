@@ -4,24 +4,42 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"flash-go/internal/models"
+	"flash-go/internal/store"
 	"flash-go/internal/utils"
 
+	"github.com/goccy/go-json"
 	redislib "github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 )
 
+// defaultJobTTL is how long job records live in Redis unless overridden.
+const defaultJobTTL = time.Hour
+
+// deadJobsKey lists IDs of jobs that exhausted their retries.
+const deadJobsKey = "dead_jobs"
+
+// Queue names one of the job queues.
+type Queue string
+
 const (
-	jobQueueName     = "jobs"
-	freeJobQueueName = "free_jobs"
-	jobTTL           = time.Hour
+	QueueMain     Queue = "jobs"
+	QueueFree     Queue = "free_jobs"
+	QueuePriority Queue = "priority_jobs"
 )
 
 // Client wraps Redis operations for jobs.
 type Client struct {
-	rdb *redislib.Client
+	rdb     *redislib.Client
+	batcher *enqueueBatcher
+	results store.ResultStore
+	jobTTL  time.Duration
+	// keyPrefix is prepended to every key the client touches, so several
+	// deployments can share one Redis.
+	keyPrefix string
 }
 
 func New(redisURL string) (*Client, error) {
@@ -35,76 +53,364 @@ func New(redisURL string) (*Client, error) {
 		logrus.WithError(err).WithField("redis_url", redisURL).Error("failed to ping Redis")
 		return nil, err
 	}
-	return &Client{rdb: rdb}, nil
+	return &Client{rdb: rdb, jobTTL: defaultJobTTL}, nil
+}
+
+// SetDefaultTTL sets how long job records are kept for jobs that do not
+// request their own ResultTTL.
+func (c *Client) SetDefaultTTL(ttl time.Duration) {
+	if ttl > 0 {
+		c.jobTTL = ttl
+	}
+}
+
+// SetKeyPrefix namespaces all queue, job and bookkeeping keys under prefix.
+// It must be called before the client is used.
+func (c *Client) SetKeyPrefix(prefix string) {
+	c.keyPrefix = prefix
+}
+
+// key returns name under the client's key prefix.
+func (c *Client) key(name string) string {
+	return c.keyPrefix + name
+}
+
+// jobKey returns the Redis key for a job ID under the client's key prefix.
+func (c *Client) jobKey(id uint64) string {
+	return c.keyPrefix + utils.JobKey(id)
+}
+
+// queueKey returns the Redis list backing queue.
+func (c *Client) queueKey(queue Queue) string {
+	return c.keyPrefix + string(queue)
+}
+
+// ttlFor returns the expiry for a job's Redis record.
+func (c *Client) ttlFor(job *models.Job) time.Duration {
+	if job.ResultTTL > 0 {
+		return time.Duration(job.ResultTTL) * time.Second
+	}
+	return c.jobTTL
+}
+
+// SetResultStore archives terminal jobs to results on StoreJob and makes
+// GetJob and GetJobs fall back to it for jobs no longer in Redis.
+func (c *Client) SetResultStore(results store.ResultStore) {
+	c.results = results
 }
 
 func (c *Client) CreateJob(ctx context.Context, job *models.Job) error {
-	return c.enqueueJob(ctx, job, jobQueueName)
+	return c.Enqueue(ctx, job, QueueMain)
 }
 
 func (c *Client) CreateFreeJob(ctx context.Context, job *models.Job) error {
-	return c.enqueueJob(ctx, job, freeJobQueueName)
+	return c.Enqueue(ctx, job, QueueFree)
 }
 
-func (c *Client) enqueueJob(_ context.Context, job *models.Job, queueName string) error {
+func (c *Client) CreatePriorityJob(ctx context.Context, job *models.Job) error {
+	return c.Enqueue(ctx, job, QueuePriority)
+}
+
+// Enqueue stores job and appends it to the given queue.
+func (c *Client) Enqueue(ctx context.Context, job *models.Job, queue Queue) error {
+	queueName := string(queue)
+	job.Queue = queueName
 	payload, err := utils.MarshalJob(job)
 	if err != nil {
 		logrus.WithError(err).WithFields(logrus.Fields{
-			"job_id": job.ID,
-			"queue":  queueName,
+			"job_id":         job.ID,
+			"correlation_id": job.CorrelationID,
+			"queue":          queueName,
 		}).Error("failed to marshal job in enqueueJob")
 		return err
 	}
-	key := utils.JobKey(job.ID)
+	key := c.jobKey(job.ID)
+	listKey := c.queueKey(queue)
+	if c.batcher != nil {
+		err = c.batcher.submit(ctx, enqueueRequest{
+			jobID:   job.ID,
+			key:     key,
+			queue:   listKey,
+			payload: payload,
+			ttl:     c.ttlFor(job),
+		})
+		if !errors.Is(err, errBatcherStopped) {
+			return err
+		}
+	}
 	enqueueCtx := context.Background()
 	pipe := c.rdb.TxPipeline()
-	pipe.Set(enqueueCtx, key, payload, jobTTL)
-	pipe.RPush(enqueueCtx, queueName, strconv.FormatUint(job.ID, 10))
+	pipe.Set(enqueueCtx, key, payload, c.ttlFor(job))
+	pipe.RPush(enqueueCtx, listKey, strconv.FormatUint(job.ID, 10))
 	_, err = pipe.Exec(enqueueCtx)
 	if err != nil {
 		logrus.WithError(err).WithFields(logrus.Fields{
-			"job_id": job.ID,
-			"queue":  queueName,
+			"job_id":         job.ID,
+			"correlation_id": job.CorrelationID,
+			"queue":          queueName,
 		}).Error("failed to execute Redis pipeline in enqueueJob")
 	}
 	return err
 }
 
+// RequeueJob stores job and pushes it back to the head of the queue it was
+// originally submitted to, so it is the next one picked up.
+func (c *Client) RequeueJob(ctx context.Context, job *models.Job) error {
+	queueName := job.Queue
+	if queueName == "" {
+		queueName = string(QueueMain)
+	}
+	payload, err := utils.MarshalJob(job)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"job_id": job.ID, "correlation_id": job.CorrelationID}).Error("failed to marshal job in RequeueJob")
+		return err
+	}
+	pipe := c.rdb.TxPipeline()
+	pipe.Set(ctx, c.jobKey(job.ID), payload, c.ttlFor(job))
+	pipe.LPush(ctx, c.queueKey(Queue(queueName)), strconv.FormatUint(job.ID, 10))
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"job_id":         job.ID,
+			"correlation_id": job.CorrelationID,
+			"queue":          queueName,
+		}).Error("failed to execute Redis pipeline in RequeueJob")
+	}
+	return err
+}
+
 // QueueLength returns the current number of jobs waiting in the queue.
-func (c *Client) QueueLength(ctx context.Context, free bool) (int64, error) {
-	queueName := jobQueueName
+func (c *Client) QueueLength(ctx context.Context, queue Queue) (int64, error) {
+	length, err := c.rdb.LLen(ctx, c.queueKey(queue)).Result()
+	if err != nil {
+		logrus.WithError(err).WithField("queue", queue).Error("failed to get queue length")
+	}
+	return length, err
+}
+
+// partialOutputKey holds the interim stdout of a running job.
+func (c *Client) partialOutputKey(jobID uint64) string {
+	return c.jobKey(jobID) + ":partial"
+}
 
-	if free {
-		queueName = freeJobQueueName
+// StorePartialOutput saves the stdout a running job has produced so far. It
+// expires with the job's own record.
+func (c *Client) StorePartialOutput(ctx context.Context, job *models.Job, stdout string) error {
+	err := c.rdb.Set(ctx, c.partialOutputKey(job.ID), stdout, c.ttlFor(job)).Err()
+	if err != nil {
+		logrus.WithError(err).WithField("job_id", job.ID).Error("failed to store partial output")
 	}
+	return err
+}
 
-	length, err := c.rdb.LLen(ctx, queueName).Result()
+// GetPartialOutput returns the latest interim stdout of a job, or "" if none
+// has been stored.
+func (c *Client) GetPartialOutput(ctx context.Context, jobID uint64) (string, error) {
+	stdout, err := c.rdb.Get(ctx, c.partialOutputKey(jobID)).Result()
 	if err != nil {
-		logrus.WithError(err).Error("failed to get queue length")
+		if errors.Is(err, redislib.Nil) {
+			return "", nil
+		}
+		logrus.WithError(err).WithField("job_id", jobID).Error("failed to get partial output")
+		return "", err
 	}
-	return length, err
+	return stdout, nil
 }
 
-// StoreJob updates the stored job by ID.
+// PushDeadJob records a job that failed permanently for later inspection.
+func (c *Client) PushDeadJob(ctx context.Context, jobID uint64) error {
+	err := c.rdb.RPush(ctx, c.key(deadJobsKey), strconv.FormatUint(jobID, 10)).Err()
+	if err != nil {
+		logrus.WithError(err).WithField("job_id", jobID).Error("failed to push dead job")
+	}
+	return err
+}
+
+// DeadJobIDs returns the IDs in the dead-letter list, oldest first.
+func (c *Client) DeadJobIDs(ctx context.Context) ([]uint64, error) {
+	values, err := c.rdb.LRange(ctx, c.key(deadJobsKey), 0, -1).Result()
+	if err != nil {
+		logrus.WithError(err).Error("failed to list dead jobs")
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// RemoveDeadJob deletes jobID from the dead-letter list, reporting whether it was there.
+func (c *Client) RemoveDeadJob(ctx context.Context, jobID uint64) (bool, error) {
+	removed, err := c.rdb.LRem(ctx, c.key(deadJobsKey), 0, strconv.FormatUint(jobID, 10)).Result()
+	if err != nil {
+		logrus.WithError(err).WithField("job_id", jobID).Error("failed to remove dead job")
+		return false, err
+	}
+	return removed > 0, nil
+}
+
+// QueuedJobIDs returns the IDs currently waiting in the queue, head first.
+func (c *Client) QueuedJobIDs(ctx context.Context, queue Queue) ([]uint64, error) {
+	return c.PeekQueue(ctx, queue, 0)
+}
+
+// PeekQueue returns up to n job IDs from the head of the queue without
+// removing them. n <= 0 returns the whole queue.
+func (c *Client) PeekQueue(ctx context.Context, queue Queue, n int64) ([]uint64, error) {
+	values, err := c.rdb.LRange(ctx, c.queueKey(queue), 0, n-1).Result()
+	if err != nil {
+		logrus.WithError(err).WithField("queue", queue).Error("failed to list queued jobs")
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			logrus.WithError(err).WithField("job_id_str", value).WithField("queue", queue).Warn("invalid job id in queue")
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// purgedMessage is the message of jobs cancelled by PurgeQueue.
+const purgedMessage = "cancelled: queue purged"
+
+// PurgeQueue empties the queue and returns the IDs it held. Each purged job
+// is stored as Cancelled so polling clients see a terminal status, and its
+// in-flight slot is released.
+func (c *Client) PurgeQueue(ctx context.Context, queue Queue) ([]uint64, error) {
+	pipe := c.rdb.TxPipeline()
+	values := pipe.LRange(ctx, c.queueKey(queue), 0, -1)
+	pipe.Del(ctx, c.queueKey(queue))
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.WithError(err).WithField("queue", queue).Error("failed to purge queue")
+		return nil, err
+	}
+
+	ids := make([]uint64, 0, len(values.Val()))
+	for _, value := range values.Val() {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			logrus.WithError(err).WithField("job_id_str", value).WithField("queue", queue).Warn("invalid job id in queue")
+			continue
+		}
+		ids = append(ids, id)
+	}
+	jobs, err := c.GetJobs(ctx, ids)
+	if err != nil {
+		return ids, err
+	}
+	now := time.Now().UnixNano()
+	for _, job := range jobs {
+		if job == nil || job.Status.Kind != models.StatusQueued {
+			continue
+		}
+		job.Status = models.JobStatus{Kind: models.StatusCancelled}
+		job.Output.Message = purgedMessage
+		job.FinishedAt = now
+		if err := c.StoreJob(ctx, job); err != nil {
+			continue
+		}
+		if job.InFlightKey != "" {
+			_ = c.ReleaseInFlight(ctx, job.InFlightKey, 1)
+		}
+	}
+	return ids, nil
+}
+
+// RemoveFromQueue removes jobID from the queue. It reports false when the job
+// was no longer queued, e.g. because a worker already popped it.
+func (c *Client) RemoveFromQueue(ctx context.Context, queue Queue, jobID uint64) (bool, error) {
+	removed, err := c.rdb.LRem(ctx, c.queueKey(queue), 1, strconv.FormatUint(jobID, 10)).Result()
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"job_id": jobID,
+			"queue":  queue,
+		}).Error("failed to remove job from queue")
+		return false, err
+	}
+	return removed > 0, nil
+}
+
+// ErrInvalidTransition is returned by StoreJob when the stored job's status
+// may not change to the new one, such as a finished job going back to
+// Processing.
+var ErrInvalidTransition = errors.New("invalid job status transition")
+
+// storeJobAttempts bounds retries when the job changes while StoreJob checks it.
+const storeJobAttempts = 3
+
+// StoreJob updates the stored job by ID. It refuses to overwrite a stored
+// status that may not transition to the job's status; see
+// models.CanTransition.
 func (c *Client) StoreJob(ctx context.Context, job *models.Job) error {
 	payload, err := utils.MarshalJob(job)
 	if err != nil {
-		logrus.WithError(err).WithField("job_id", job.ID).Error("failed to marshal job in StoreJob")
+		logrus.WithError(err).WithFields(logrus.Fields{"job_id": job.ID, "correlation_id": job.CorrelationID}).Error("failed to marshal job in StoreJob")
+		return err
+	}
+	key := c.jobKey(job.ID)
+	var from string
+	store := func(tx *redislib.Tx) error {
+		current, err := tx.Get(ctx, key).Bytes()
+		if err != nil && !errors.Is(err, redislib.Nil) {
+			return err
+		}
+		if err == nil {
+			var stored struct {
+				Status models.JobStatus `json:"status"`
+			}
+			if json.Unmarshal(current, &stored) == nil {
+				from = stored.Status.Kind
+				if !models.CanTransition(from, job.Status.Kind) {
+					return ErrInvalidTransition
+				}
+			}
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redislib.Pipeliner) error {
+			pipe.Set(ctx, key, payload, c.ttlFor(job))
+			return nil
+		})
+		return err
+	}
+	for attempt := 0; attempt < storeJobAttempts; attempt++ {
+		if err = c.rdb.Watch(ctx, store, key); !errors.Is(err, redislib.TxFailedErr) {
+			break
+		}
+	}
+	if errors.Is(err, ErrInvalidTransition) {
+		logrus.WithFields(logrus.Fields{
+			"job_id":         job.ID,
+			"correlation_id": job.CorrelationID,
+			"from":           from,
+			"to":             job.Status.Kind,
+		}).Warn("refusing to overwrite job status")
 		return err
 	}
-	err = c.rdb.Set(ctx, utils.JobKey(job.ID), payload, jobTTL).Err()
 	if err != nil {
-		logrus.WithError(err).WithField("job_id", job.ID).Error("failed to store job in Redis")
+		logrus.WithError(err).WithFields(logrus.Fields{"job_id": job.ID, "correlation_id": job.CorrelationID}).Error("failed to store job in Redis")
+	}
+	if c.results != nil && job.Status.IsTerminal() {
+		if archiveErr := c.results.Save(ctx, job); archiveErr != nil {
+			logrus.WithError(archiveErr).WithFields(logrus.Fields{"job_id": job.ID, "correlation_id": job.CorrelationID}).Error("failed to archive job result")
+		}
 	}
 	return err
 }
 
 // GetJob fetches a job by ID. Returns (nil, nil) if not found.
 func (c *Client) GetJob(ctx context.Context, jobID uint64) (*models.Job, error) {
-	data, err := c.rdb.Get(ctx, utils.JobKey(jobID)).Bytes()
+	data, err := c.rdb.Get(ctx, c.jobKey(jobID)).Bytes()
 	if err != nil {
 		if errors.Is(err, redislib.Nil) {
-			return nil, nil
+			return c.loadArchived(ctx, jobID)
 		}
 		logrus.WithError(err).WithField("job_id", jobID).Error("failed to get job from Redis")
 		return nil, err
@@ -117,37 +423,45 @@ func (c *Client) GetJob(ctx context.Context, jobID uint64) (*models.Job, error)
 	return &job, nil
 }
 
-// GetJobFromQueue blocks until a job is available or timeout occurs.
-// Uses FIFO (RPush + BLPop) to avoid starving older jobs.
+// GetJobFromMainQueue blocks until a main queue job is available or timeout occurs.
 func (c *Client) GetJobFromMainQueue(ctx context.Context, timeout time.Duration) (*models.Job, error) {
-	return c.GetJobFromQueue(ctx, timeout, jobQueueName)
+	return c.GetJobFromQueues(ctx, timeout, QueueMain)
 }
 
-
-
+// GetJobFromFreeQueue blocks until a free queue job is available or timeout occurs.
 func (c *Client) GetJobFromFreeQueue(ctx context.Context, timeout time.Duration) (*models.Job, error) {
-	return c.GetJobFromQueue(ctx, timeout, freeJobQueueName)
+	return c.GetJobFromQueues(ctx, timeout, QueueFree)
+}
+
+// GetJobFromPriorityQueue blocks until a priority queue job is available or timeout occurs.
+func (c *Client) GetJobFromPriorityQueue(ctx context.Context, timeout time.Duration) (*models.Job, error) {
+	return c.GetJobFromQueues(ctx, timeout, QueuePriority)
 }
 
-// GetJobFromQueue blocks until a job is available or timeout occurs.
+// GetJobFromQueues blocks until a job is available in any of the queues or
+// timeout occurs. Queues are checked in the order given.
 // Uses FIFO (RPush + BLPop) to avoid starving older jobs.
-func (c *Client) GetJobFromQueue(ctx context.Context, timeout time.Duration, queueName string) (*models.Job, error) {
-	result, err := c.rdb.BLPop(ctx, timeout, queueName).Result()
+func (c *Client) GetJobFromQueues(ctx context.Context, timeout time.Duration, queues ...Queue) (*models.Job, error) {
+	queueNames := make([]string, len(queues))
+	for i, queue := range queues {
+		queueNames[i] = c.queueKey(queue)
+	}
+	result, err := c.rdb.BLPop(ctx, timeout, queueNames...).Result()
 	if err != nil {
 		if errors.Is(err, redislib.Nil) {
 			return nil, nil
 		}
-		logrus.WithError(err).WithField("queue", queueName).Error("failed to get job from queue")
+		logrus.WithError(err).WithField("queues", queueNames).Error("failed to get job from queue")
 		return nil, err
 	}
 	if len(result) < 2 {
 		logrus.WithField("result_length", len(result)).Error("unexpected BLPOP response")
-		return nil, errors.New("unexpected BLPOP response in GetJobFromQueue")
+		return nil, errors.New("unexpected BLPOP response in GetJobFromQueues")
 	}
 	jobID, err := strconv.ParseUint(result[1], 10, 64)
 	if err != nil {
-		logrus.WithError(err).WithField("job_id_str", result[1]).WithField("queue", queueName).Error("invalid job id in queue")
-		return nil, errors.New("invalid job id in queue in GetJobFromQueue")
+		logrus.WithError(err).WithField("job_id_str", result[1]).WithField("queue", result[0]).Error("invalid job id in queue")
+		return nil, errors.New("invalid job id in queue in GetJobFromQueues")
 	}
 	return c.GetJob(ctx, jobID)
 }
@@ -159,7 +473,7 @@ func (c *Client) GetJobs(ctx context.Context, jobIDs []uint64) ([]*models.Job, e
 	}
 	keys := make([]string, 0, len(jobIDs))
 	for _, jobID := range jobIDs {
-		keys = append(keys, utils.JobKey(jobID))
+		keys = append(keys, c.jobKey(jobID))
 	}
 	values, err := c.rdb.MGet(ctx, keys...).Result()
 	if err != nil {
@@ -188,5 +502,103 @@ func (c *Client) GetJobs(ctx context.Context, jobIDs []uint64) ([]*models.Job, e
 		}
 		jobs[i] = &job
 	}
+	if c.results != nil {
+		for i, job := range jobs {
+			if job != nil {
+				continue
+			}
+			if jobs[i], err = c.loadArchived(ctx, jobIDs[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
 	return jobs, nil
 }
+
+// loadArchived looks a job up in the result store, if one is configured.
+func (c *Client) loadArchived(ctx context.Context, jobID uint64) (*models.Job, error) {
+	if c.results == nil {
+		return nil, nil
+	}
+	job, err := c.results.Load(ctx, jobID)
+	if err != nil {
+		logrus.WithError(err).WithField("job_id", jobID).Error("failed to load archived job")
+		return nil, err
+	}
+	return job, nil
+}
+
+// deleteScanCount is the SCAN batch size used by DeleteJobsBefore.
+const deleteScanCount = 500
+
+// globEscaper quotes the characters SCAN MATCH treats as wildcards.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// DeleteJobsBefore deletes jobs that finished before the unix nanosecond
+// timestamp, together with their partial output, and returns how many there
+// were. With dryRun it only counts them. Keys are walked with SCAN so Redis
+// is never blocked; archived results in the result store are left alone.
+func (c *Client) DeleteJobsBefore(ctx context.Context, before int64, dryRun bool) (int64, error) {
+	var (
+		deleted int64
+		cursor  uint64
+	)
+	prefix := c.key(utils.JobKeyPrefix)
+	for {
+		keys, next, err := c.rdb.Scan(ctx, cursor, globEscaper.Replace(prefix)+"*", deleteScanCount).Result()
+		if err != nil {
+			logrus.WithError(err).Error("failed to scan job keys")
+			return deleted, err
+		}
+		jobKeys := keys[:0]
+		for _, key := range keys {
+			if _, err := strconv.ParseUint(strings.TrimPrefix(key, prefix), 10, 64); err == nil {
+				jobKeys = append(jobKeys, key)
+			}
+		}
+		if len(jobKeys) > 0 {
+			n, err := c.deleteFinishedBefore(ctx, jobKeys, before, dryRun)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+		if cursor = next; cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// deleteFinishedBefore deletes the jobs among keys that finished before the
+// timestamp and returns how many matched.
+func (c *Client) deleteFinishedBefore(ctx context.Context, keys []string, before int64, dryRun bool) (int64, error) {
+	values, err := c.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		logrus.WithError(err).Error("failed to fetch jobs for deletion")
+		return 0, err
+	}
+	var expired []string
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var job struct {
+			FinishedAt int64 `json:"finished_at"`
+		}
+		if json.Unmarshal([]byte(data), &job) != nil {
+			continue
+		}
+		if job.FinishedAt > 0 && job.FinishedAt < before {
+			expired = append(expired, keys[i], keys[i]+":partial")
+		}
+	}
+	if dryRun || len(expired) == 0 {
+		return int64(len(expired) / 2), nil
+	}
+	if err := c.rdb.Del(ctx, expired...).Err(); err != nil {
+		logrus.WithError(err).Error("failed to delete jobs")
+		return 0, err
+	}
+	return int64(len(expired) / 2), nil
+}
@@ -0,0 +1,410 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"flash-go/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestClient returns a Client backed by a fresh in-memory Redis.
+func newTestClient(t testing.TB) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	c, err := New("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.rdb.Close() })
+	return c, mr
+}
+
+func queuedJob(id uint64) *models.Job {
+	return &models.Job{ID: id, Status: models.JobStatus{Kind: models.StatusQueued}}
+}
+
+func TestEnqueueAndDequeue(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	for _, id := range []uint64{1, 2} {
+		if err := c.Enqueue(ctx, queuedJob(id), QueueMain); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Enqueue(ctx, queuedJob(3), QueuePriority); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.QueueLength(ctx, QueueMain); err != nil || n != 2 {
+		t.Fatalf("QueueLength = %d, %v; want 2", n, err)
+	}
+
+	// Queues are checked in the order given, each FIFO.
+	for _, want := range []uint64{3, 1, 2} {
+		job, err := c.GetJobFromQueues(ctx, 10*time.Millisecond, QueuePriority, QueueMain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job == nil || job.ID != want {
+			t.Fatalf("dequeued %+v, want job %d", job, want)
+		}
+	}
+	if job, err := c.GetJobFromQueues(ctx, 10*time.Millisecond, QueueMain); err != nil || job != nil {
+		t.Errorf("empty queue returned %+v, %v", job, err)
+	}
+}
+
+func TestPeekQueue(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	for id := uint64(1); id <= 5; id++ {
+		if err := c.Enqueue(ctx, queuedJob(id), QueueFree); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids, err := c.PeekQueue(ctx, QueueFree, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []uint64{1, 2, 3}) {
+		t.Errorf("PeekQueue(3) = %v, want [1 2 3]", ids)
+	}
+	all, err := c.PeekQueue(ctx, QueueFree, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(all, []uint64{1, 2, 3, 4, 5}) {
+		t.Errorf("PeekQueue(0) = %v, want all five", all)
+	}
+	if n, _ := c.QueueLength(ctx, QueueFree); n != 5 {
+		t.Errorf("PeekQueue removed jobs: length %d", n)
+	}
+	if ids, err := c.PeekQueue(ctx, QueueMain, 3); err != nil || len(ids) != 0 {
+		t.Errorf("PeekQueue(empty) = %v, %v", ids, err)
+	}
+}
+
+func TestPurgeQueue(t *testing.T) {
+	c, mr := newTestClient(t)
+	ctx := context.Background()
+	for id := uint64(1); id <= 3; id++ {
+		job := queuedJob(id)
+		job.InFlightKey = "client"
+		if err := c.Enqueue(ctx, job, QueueMain); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ok, err := c.AcquireInFlight(ctx, "client", 3, 10); err != nil || !ok {
+		t.Fatalf("AcquireInFlight = %v, %v", ok, err)
+	}
+	if err := c.Enqueue(ctx, queuedJob(4), QueueFree); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := c.PurgeQueue(ctx, QueueMain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(purged, []uint64{1, 2, 3}) {
+		t.Errorf("PurgeQueue = %v, want [1 2 3]", purged)
+	}
+	if mr.Exists(string(QueueMain)) {
+		t.Error("main queue still exists")
+	}
+	if n, _ := c.QueueLength(ctx, QueueFree); n != 1 {
+		t.Errorf("free queue length = %d, want 1", n)
+	}
+	for _, id := range purged {
+		job, err := c.GetJob(ctx, id)
+		if err != nil || job == nil {
+			t.Fatalf("GetJob(%d) after purge = %v, %v", id, job, err)
+		}
+		if job.Status.Kind != models.StatusCancelled || job.Output.Message != purgedMessage || job.FinishedAt == 0 {
+			t.Errorf("job %d = %+v, want cancelled by the purge", id, job)
+		}
+	}
+	// All three slots were given back, so the full limit is available again.
+	if ok, err := c.AcquireInFlight(ctx, "client", 10, 10); err != nil || !ok {
+		t.Errorf("in-flight slots not released: AcquireInFlight = %v, %v", ok, err)
+	}
+	if purged, err := c.PurgeQueue(ctx, QueueMain); err != nil || len(purged) != 0 {
+		t.Errorf("PurgeQueue(empty) = %v, %v", purged, err)
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	c, mr := newTestClient(t)
+	other, err := New("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.rdb.Close()
+	c.SetKeyPrefix("tenant-a:")
+	other.SetKeyPrefix("tenant-b:")
+	ctx := context.Background()
+
+	if err := c.Enqueue(ctx, queuedJob(7), QueueMain); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.PushDeadJob(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"tenant-a:job:7", "tenant-a:jobs", "tenant-a:dead_jobs"} {
+		if !mr.Exists(key) {
+			t.Errorf("key %q does not exist", key)
+		}
+	}
+	if mr.Exists("job:7") || mr.Exists("jobs") {
+		t.Error("unprefixed keys were written")
+	}
+
+	if job, err := other.GetJob(ctx, 7); err != nil || job != nil {
+		t.Errorf("other tenant sees job: %+v, %v", job, err)
+	}
+	if n, _ := other.QueueLength(ctx, QueueMain); n != 0 {
+		t.Errorf("other tenant queue length = %d", n)
+	}
+	if job, err := other.GetJobFromQueues(ctx, 10*time.Millisecond, QueueMain); err != nil || job != nil {
+		t.Errorf("other tenant dequeued %+v, %v", job, err)
+	}
+	if job, err := c.GetJob(ctx, 7); err != nil || job == nil {
+		t.Errorf("own tenant cannot read job: %v", err)
+	}
+}
+
+func TestJobTTL(t *testing.T) {
+	c, mr := newTestClient(t)
+	c.SetDefaultTTL(30 * time.Minute)
+	ctx := context.Background()
+
+	if err := c.Enqueue(ctx, queuedJob(1), QueueMain); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("job:1"); ttl != 30*time.Minute {
+		t.Errorf("default TTL = %v, want 30m", ttl)
+	}
+
+	job := queuedJob(2)
+	job.ResultTTL = 120
+	if err := c.Enqueue(ctx, job, QueueMain); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("job:2"); ttl != 2*time.Minute {
+		t.Errorf("requested TTL = %v, want 2m", ttl)
+	}
+
+	job.Status = models.JobStatus{Kind: models.StatusAccepted}
+	if err := c.StoreJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("job:2"); ttl != 2*time.Minute {
+		t.Errorf("TTL after StoreJob = %v, want 2m", ttl)
+	}
+
+	if err := c.StorePartialOutput(ctx, job, "partial"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("job:2:partial"); ttl != 2*time.Minute {
+		t.Errorf("partial output TTL = %v, want 2m", ttl)
+	}
+	if err := c.StorePartialOutput(ctx, queuedJob(1), "partial"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("job:1:partial"); ttl != 30*time.Minute {
+		t.Errorf("default partial output TTL = %v, want 30m", ttl)
+	}
+}
+
+func TestStoreJobTransitions(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	job := queuedJob(1)
+	if err := c.Enqueue(ctx, job, QueueMain); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		to      string
+		wantErr error
+	}{
+		{models.StatusProcessing, nil},
+		{models.StatusInternalError, nil},
+		{models.StatusProcessing, nil},
+		{models.StatusAccepted, nil},
+		{models.StatusProcessing, ErrInvalidTransition},
+		{models.StatusQueued, ErrInvalidTransition},
+		{models.StatusCancelled, ErrInvalidTransition},
+	}
+	for _, step := range steps {
+		job.Status = models.JobStatus{Kind: step.to}
+		if err := c.StoreJob(ctx, job); !errors.Is(err, step.wantErr) {
+			t.Errorf("StoreJob(%s) = %v, want %v", step.to, err, step.wantErr)
+		}
+	}
+	stored, err := c.GetJob(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status.Kind != models.StatusAccepted {
+		t.Errorf("stored status = %s, want Accepted", stored.Status.Kind)
+	}
+}
+
+func TestGetJobs(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	for _, id := range []uint64{1, 3} {
+		if err := c.Enqueue(ctx, queuedJob(id), QueueMain); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jobs, err := c.GetJobs(ctx, []uint64{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 3 || jobs[0] == nil || jobs[1] != nil || jobs[2] == nil || jobs[2].ID != 3 {
+		t.Errorf("GetJobs = %v", jobs)
+	}
+}
+
+func TestDeleteJobsBefore(t *testing.T) {
+	c, mr := newTestClient(t)
+	c.SetKeyPrefix("p:")
+	ctx := context.Background()
+
+	finished := func(id uint64, at int64) {
+		t.Helper()
+		job := queuedJob(id)
+		if err := c.Enqueue(ctx, job, QueueMain); err != nil {
+			t.Fatal(err)
+		}
+		if at > 0 {
+			job.Status = models.JobStatus{Kind: models.StatusAccepted}
+			job.FinishedAt = at
+			if err := c.StoreJob(ctx, job); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	finished(1, 100)
+	finished(2, 200)
+	finished(3, 300)
+	finished(4, 0)
+	if err := c.StorePartialOutput(ctx, &models.Job{ID: 1}, "partial"); err != nil {
+		t.Fatal(err)
+	}
+	// Keys from another deployment and non-job keys are never touched.
+	mr.Set("job:9", `{"finished_at": 1}`)
+	mr.Set("p:job:1:partial:extra", "x")
+
+	n, err := c.DeleteJobsBefore(ctx, 250, true)
+	if err != nil || n != 2 {
+		t.Fatalf("dry run = %d, %v; want 2", n, err)
+	}
+	if !mr.Exists("p:job:1") {
+		t.Fatal("dry run deleted a job")
+	}
+
+	n, err = c.DeleteJobsBefore(ctx, 250, false)
+	if err != nil || n != 2 {
+		t.Fatalf("DeleteJobsBefore = %d, %v; want 2", n, err)
+	}
+	for key, want := range map[string]bool{
+		"p:job:1": false, "p:job:1:partial": false, "p:job:2": false,
+		"p:job:3": true, "p:job:4": true, "job:9": true, "p:job:1:partial:extra": true,
+	} {
+		if mr.Exists(key) != want {
+			t.Errorf("key %q exists = %v, want %v", key, !want, want)
+		}
+	}
+}
+
+func TestDeadJobs(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+	for _, id := range []uint64{5, 6} {
+		if err := c.PushDeadJob(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ids, err := c.DeadJobIDs(ctx); err != nil || !slices.Equal(ids, []uint64{5, 6}) {
+		t.Errorf("DeadJobIDs = %v, %v", ids, err)
+	}
+	if removed, err := c.RemoveDeadJob(ctx, 5); err != nil || !removed {
+		t.Errorf("RemoveDeadJob(5) = %v, %v", removed, err)
+	}
+	if removed, err := c.RemoveDeadJob(ctx, 5); err != nil || removed {
+		t.Errorf("RemoveDeadJob(5) again = %v, %v", removed, err)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	if ok, err := c.AcquireInFlight(ctx, "k", 2, 3); err != nil || !ok {
+		t.Fatalf("AcquireInFlight(2) = %v, %v", ok, err)
+	}
+	if ok, _ := c.AcquireInFlight(ctx, "k", 2, 3); ok {
+		t.Error("AcquireInFlight went over the limit")
+	}
+	if err := c.ReleaseInFlight(ctx, "k", 1); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.AcquireInFlight(ctx, "k", 2, 3); !ok {
+		t.Error("AcquireInFlight refused after a release")
+	}
+	if err := c.ReleaseInFlight(ctx, "k", 10); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.AcquireInFlight(ctx, "k", 3, 3); !ok {
+		t.Error("counter did not drop to zero")
+	}
+}
+
+func TestClaimIdempotencyKey(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	id, claimed, err := c.ClaimIdempotencyKey(ctx, "k", 1, time.Minute)
+	if err != nil || !claimed || id != 1 {
+		t.Fatalf("first claim = %d, %v, %v", id, claimed, err)
+	}
+	id, claimed, err = c.ClaimIdempotencyKey(ctx, "k", 2, time.Minute)
+	if err != nil || claimed || id != 1 {
+		t.Errorf("second claim = %d, %v, %v; want job 1", id, claimed, err)
+	}
+	if err := c.ReleaseIdempotencyKey(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, claimed, _ := c.ClaimIdempotencyKey(ctx, "k", 3, time.Minute); !claimed {
+		t.Error("claim after release failed")
+	}
+}
+
+func TestTakeToken(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if ok, _, err := c.TakeToken(ctx, "ip:1", 3); err != nil || !ok {
+			t.Fatalf("token %d = %v, %v", i+1, ok, err)
+		}
+	}
+	ok, retryAfter, err := c.TakeToken(ctx, "ip:1", 3)
+	if err != nil || ok {
+		t.Fatalf("fourth token = %v, %v; want refused", ok, err)
+	}
+	if retryAfter <= 0 || retryAfter > 20*time.Second {
+		t.Errorf("retry after %v, want up to 20s", retryAfter)
+	}
+	if ok, _, _ := c.TakeToken(ctx, "ip:2", 3); !ok {
+		t.Error("another client shares the bucket")
+	}
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	redislib "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// idempotencyPrefix namespaces idempotency key to job ID mappings.
+const idempotencyPrefix = "idempotency:"
+
+// claimIdempotencyScript stores ARGV[1] under KEYS[1] unless the key is set,
+// and returns whichever job ID the key holds afterwards.
+var claimIdempotencyScript = redislib.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return ARGV[1]
+end
+return redis.call('GET', KEYS[1])
+`)
+
+// ClaimIdempotencyKey maps key to jobID for ttl unless it already maps to a
+// job. It returns the job ID the key maps to and whether jobID claimed it.
+func (c *Client) ClaimIdempotencyKey(ctx context.Context, key string, jobID uint64, ttl time.Duration) (uint64, bool, error) {
+	res, err := claimIdempotencyScript.Run(ctx, c.rdb, []string{c.key(idempotencyPrefix + key)},
+		strconv.FormatUint(jobID, 10), ttl.Milliseconds()).Text()
+	if err != nil {
+		logrus.WithError(err).WithField("job_id", jobID).Error("failed to claim idempotency key")
+		return 0, false, err
+	}
+	existing, err := strconv.ParseUint(res, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return existing, existing == jobID, nil
+}
+
+// ReleaseIdempotencyKey removes a claim whose job could not be enqueued so
+// the client's retry creates it.
+func (c *Client) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	return c.rdb.Del(ctx, c.key(idempotencyPrefix+key)).Err()
+}
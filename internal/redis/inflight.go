@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	redislib "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// inFlightPrefix namespaces per-client in-flight job counters.
+	inFlightPrefix = "inflight:"
+	// inFlightTTL expires counters that stop being updated, so jobs dropped
+	// without finishing (e.g. a purged queue) cannot block a client forever.
+	inFlightTTL = 24 * time.Hour
+)
+
+// acquireInFlightScript adds ARGV[1] to the counter unless that would take it
+// above ARGV[2]. It returns 1 when the jobs were admitted.
+var acquireInFlightScript = redislib.NewScript(`
+local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+if count + tonumber(ARGV[1]) > tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('INCRBY', KEYS[1], ARGV[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// releaseInFlightScript subtracts ARGV[1] from the counter without going
+// below zero.
+var releaseInFlightScript = redislib.NewScript(`
+local count = tonumber(redis.call('GET', KEYS[1]) or '0')
+local next = math.max(count - tonumber(ARGV[1]), 0)
+if next == 0 then
+	redis.call('DEL', KEYS[1])
+else
+	redis.call('SET', KEYS[1], next, 'KEEPTTL')
+end
+return next
+`)
+
+// AcquireInFlight admits n more jobs for key if its in-flight count stays
+// within limit.
+func (c *Client) AcquireInFlight(ctx context.Context, key string, n, limit int) (bool, error) {
+	res, err := acquireInFlightScript.Run(ctx, c.rdb, []string{c.key(inFlightPrefix + key)},
+		n, limit, inFlightTTL.Milliseconds()).Int()
+	if err != nil {
+		logrus.WithError(err).WithField("key", key).Error("failed to acquire in-flight slot")
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// ReleaseInFlight returns n in-flight slots for key.
+func (c *Client) ReleaseInFlight(ctx context.Context, key string, n int) error {
+	err := releaseInFlightScript.Run(ctx, c.rdb, []string{c.key(inFlightPrefix + key)}, n).Err()
+	if err != nil {
+		logrus.WithError(err).WithField("key", key).Error("failed to release in-flight slot")
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	redislib "github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// rateLimitPrefix namespaces token bucket keys.
+const rateLimitPrefix = "ratelimit:"
+
+// takeTokenScript refills a token bucket by elapsed time and takes one token.
+// It returns {allowed, milliseconds until the next token}. Redis' own clock
+// is used so every API instance sees the same time.
+var takeTokenScript = redislib.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or capacity
+local ts = tonumber(data[2]) or now
+tokens = math.min(capacity, tokens + (now - ts) * rate)
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate))
+return {allowed, wait}
+`)
+
+// TakeToken takes one token from the bucket identified by key, which holds up
+// to perMinute tokens and refills at perMinute per minute. When the bucket is
+// empty it reports how long until the next token.
+func (c *Client) TakeToken(ctx context.Context, key string, perMinute int) (bool, time.Duration, error) {
+	perMs := float64(perMinute) / float64(time.Minute/time.Millisecond)
+	res, err := takeTokenScript.Run(ctx, c.rdb, []string{c.key(rateLimitPrefix + key)},
+		perMinute, strconv.FormatFloat(perMs, 'f', -1, 64)).Int64Slice()
+	if err != nil {
+		logrus.WithError(err).WithField("key", key).Error("failed to take rate limit token")
+		return false, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
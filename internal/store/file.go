@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"flash-go/internal/models"
+	"flash-go/internal/utils"
+)
+
+// FileStore keeps one JSON file per job in a directory.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates dir if needed and returns a store writing into it.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create result store dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(jobID uint64) string {
+	return filepath.Join(s.dir, strconv.FormatUint(jobID, 10)+".json")
+}
+
+// Save writes the job atomically via a temporary file and rename.
+func (s *FileStore) Save(_ context.Context, job *models.Job) error {
+	payload, err := utils.MarshalJob(job)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(job.ID))
+}
+
+func (s *FileStore) Load(_ context.Context, jobID uint64) (*models.Job, error) {
+	data, err := os.ReadFile(s.path(jobID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var job models.Job
+	if err := utils.UnmarshalJob(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
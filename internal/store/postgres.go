@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"flash-go/internal/models"
+	"flash-go/internal/utils"
+
+	_ "github.com/lib/pq"
+)
+
+const createResultsTable = `CREATE TABLE IF NOT EXISTS job_results (
+	id          TEXT PRIMARY KEY,
+	payload     JSONB NOT NULL,
+	finished_at BIGINT NOT NULL
+)`
+
+// PostgresStore keeps finished jobs in a job_results table.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore connects to dsn and creates the results table if missing.
+func NewPostgresStore(ctx context.Context, dsn string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, createResultsTable); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+func (s *PostgresStore) Save(ctx context.Context, job *models.Job) error {
+	payload, err := utils.MarshalJob(job)
+	if err != nil {
+		return err
+	}
+	// Job IDs use the full uint64 range, so they are stored as text.
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO job_results (id, payload, finished_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (id) DO UPDATE SET payload = EXCLUDED.payload, finished_at = EXCLUDED.finished_at`,
+		strconv.FormatUint(job.ID, 10), payload, job.FinishedAt)
+	return err
+}
+
+func (s *PostgresStore) Load(ctx context.Context, jobID uint64) (*models.Job, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx,
+		`SELECT payload FROM job_results WHERE id = $1`,
+		strconv.FormatUint(jobID, 10)).Scan(&payload)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var job models.Job
+	if err := utils.UnmarshalJob(payload, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Close releases the database connection pool.
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
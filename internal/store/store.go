@@ -0,0 +1,15 @@
+package store
+
+import (
+	"context"
+
+	"flash-go/internal/models"
+)
+
+// ResultStore archives finished jobs beyond their Redis TTL.
+type ResultStore interface {
+	// Save persists the final state of a job, replacing any earlier copy.
+	Save(ctx context.Context, job *models.Job) error
+	// Load returns an archived job, or (nil, nil) if it was never archived.
+	Load(ctx context.Context, jobID uint64) (*models.Job, error)
+}
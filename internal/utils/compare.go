@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"flash-go/internal/models"
+)
+
+// ValidateComparisonMode returns an error if mode is not a known comparison mode.
+// The empty string is accepted and means the default mode.
+func ValidateComparisonMode(mode string) error {
+	switch mode {
+	case "", models.ComparisonExact, models.ComparisonTrailingTrim,
+		models.ComparisonIgnoreAllWhitespace, models.ComparisonToken,
+		models.ComparisonTrailingNewline, models.ComparisonFloatTolerance:
+		return nil
+	default:
+		return fmt.Errorf("unknown comparison_mode %q", mode)
+	}
+}
+
+// OutputMatches reports whether stdout matches expected under mode.
+// tolerance only applies to float_tolerance; 0 means the default.
+// Unknown modes fall back to the default trailing_trim comparison.
+func OutputMatches(stdout, expected, mode string, tolerance float64) bool {
+	switch mode {
+	case models.ComparisonExact:
+		return stdout == expected
+	case models.ComparisonIgnoreAllWhitespace:
+		return stripWhitespace(stdout) == stripWhitespace(expected)
+	case models.ComparisonToken:
+		return tokensEqual(stdout, expected)
+	case models.ComparisonTrailingNewline:
+		return trimTrailingNewline(stdout) == trimTrailingNewline(expected)
+	case models.ComparisonFloatTolerance:
+		if tolerance <= 0 {
+			tolerance = models.DefaultFloatTolerance
+		}
+		got := strings.Fields(stdout)
+		want := strings.Fields(expected)
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if !tokensMatch(got[i], want[i], tolerance) {
+				return false
+			}
+		}
+		return true
+	default:
+		return strings.TrimSpace(stdout) == strings.TrimSpace(expected)
+	}
+}
+
+// tokensEqual reports whether a and b have the same whitespace-separated
+// tokens.
+func tokensEqual(a, b string) bool {
+	return slices.Equal(strings.Fields(a), strings.Fields(b))
+}
+
+// tokensMatch compares two tokens exactly unless both are finite numbers,
+// which match when within tolerance absolutely or relative to the larger.
+func tokensMatch(got, want string, tolerance float64) bool {
+	if got == want {
+		return true
+	}
+	g, err := strconv.ParseFloat(got, 64)
+	if err != nil || math.IsNaN(g) || math.IsInf(g, 0) {
+		return false
+	}
+	w, err := strconv.ParseFloat(want, 64)
+	if err != nil || math.IsNaN(w) || math.IsInf(w, 0) {
+		return false
+	}
+	diff := math.Abs(g - w)
+	return diff <= tolerance || diff <= tolerance*math.Max(math.Abs(g), math.Abs(w))
+}
+
+// trimTrailingNewline removes one trailing "\n" or "\r\n" from s.
+func trimTrailingNewline(s string) string {
+	if s, ok := strings.CutSuffix(s, "\n"); ok {
+		return strings.TrimSuffix(s, "\r")
+	}
+	return s
+}
+
+func stripWhitespace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// NormalizeLineEndings converts CRLF line endings to LF.
+func NormalizeLineEndings(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
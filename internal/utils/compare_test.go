@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"testing"
+
+	"flash-go/internal/models"
+)
+
+type outputMatchCase struct {
+	name      string
+	mode      string
+	stdout    string
+	expected  string
+	tolerance float64
+	want      bool
+}
+
+func checkOutputMatches(t *testing.T, tests []outputMatchCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OutputMatches(tt.stdout, tt.expected, tt.mode, tt.tolerance); got != tt.want {
+				t.Errorf("OutputMatches(%q, %q, %q) = %v, want %v", tt.stdout, tt.expected, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputMatches(t *testing.T) {
+	checkOutputMatches(t, []outputMatchCase{
+		{"exact equal", models.ComparisonExact, "1 2\n", "1 2\n", 0, true},
+		{"exact trailing newline", models.ComparisonExact, "1 2\n", "1 2", 0, false},
+		{"exact crlf", models.ComparisonExact, "1 2\r\n", "1 2\n", 0, false},
+		{"default trims", "", "  1 2\n\n", "1 2", 0, true},
+		{"trailing_trim trims", models.ComparisonTrailingTrim, "1 2\n", "1 2", 0, true},
+		{"trailing_trim keeps inner spaces", models.ComparisonTrailingTrim, "1  2", "1 2", 0, false},
+		{"trailing_trim crlf inside", models.ComparisonTrailingTrim, "1\r\n2\r\n", "1\n2\n", 0, false},
+		{"ignore_all_whitespace", models.ComparisonIgnoreAllWhitespace, "1 2\r\n3", "12\n3\n", 0, true},
+		{"ignore_all_whitespace differs", models.ComparisonIgnoreAllWhitespace, "1 2", "1 3", 0, false},
+		{"token spacing", models.ComparisonToken, "1   2\r\n3\n", "1 2 3", 0, true},
+		{"token merged", models.ComparisonToken, "12 3", "1 2 3", 0, false},
+		{"unknown mode falls back", "bogus", "1 2\n", "1 2", 0, true},
+	})
+}
+
+func TestOutputMatchesTrailingNewline(t *testing.T) {
+	checkOutputMatches(t, []outputMatchCase{
+		{"trailing_newline lf", models.ComparisonTrailingNewline, "ok\n", "ok", 0, true},
+		{"trailing_newline crlf", models.ComparisonTrailingNewline, "ok\r\n", "ok\n", 0, true},
+		{"trailing_newline only one", models.ComparisonTrailingNewline, "ok\n\n", "ok", 0, false},
+		{"trailing_newline leading space", models.ComparisonTrailingNewline, " ok\n", "ok\n", 0, false},
+	})
+}
+
+func TestOutputMatchesFloatTolerance(t *testing.T) {
+	checkOutputMatches(t, []outputMatchCase{
+		{"float within default", models.ComparisonFloatTolerance, "0.3333333", "0.33333333", 0, true},
+		{"float 1e-9 apart", models.ComparisonFloatTolerance, "1.000000001", "1.0", 0, true},
+		{"float outside tolerance", models.ComparisonFloatTolerance, "1.001", "1.0", 1e-9, false},
+		{"float relative", models.ComparisonFloatTolerance, "1000000.5", "1000000", 1e-6, true},
+		{"float mixed text", models.ComparisonFloatTolerance, "answer 3.0000000001\nyes", "answer 3 yes", 0, true},
+		{"float text must match", models.ComparisonFloatTolerance, "Answer 3", "answer 3", 0, false},
+		{"float token count", models.ComparisonFloatTolerance, "1 2", "1 2 3", 0, false},
+		{"float nan", models.ComparisonFloatTolerance, "NaN", "0", 0, false},
+	})
+}
+
+func TestValidateComparisonMode(t *testing.T) {
+	for _, mode := range []string{"", models.ComparisonExact, models.ComparisonToken, models.ComparisonFloatTolerance} {
+		if err := ValidateComparisonMode(mode); err != nil {
+			t.Errorf("ValidateComparisonMode(%q) = %v, want nil", mode, err)
+		}
+	}
+	if err := ValidateComparisonMode("fuzzy"); err == nil {
+		t.Error("ValidateComparisonMode(\"fuzzy\") = nil, want error")
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	if got := NormalizeLineEndings("a\r\nb\r\n"); got != "a\nb\n" {
+		t.Errorf("NormalizeLineEndings = %q, want %q", got, "a\nb\n")
+	}
+}
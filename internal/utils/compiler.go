@@ -0,0 +1,27 @@
+package utils
+
+import "errors"
+
+// maxCompilerOptionsLength bounds the extra flags accepted per submission.
+const maxCompilerOptionsLength = 512
+
+// ValidateCompilerOptions checks user-supplied compiler flags before they are
+// spliced into the `sh -c` compile command. Only letters, digits, spaces and
+// the punctuation used by ordinary flags (- _ = + . , : /) are allowed, which
+// rules out command separators (; & |), substitutions ($ and backticks),
+// redirections (< >), quotes, globs and newlines. Flags are therefore always
+// passed to the compiler as plain words and can never start a second command.
+func ValidateCompilerOptions(opts string) error {
+	if len(opts) > maxCompilerOptionsLength {
+		return errors.New("compiler_options is too long")
+	}
+	for _, r := range opts {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == ' ', r == '-', r == '_', r == '=', r == '+', r == '.', r == ',', r == ':', r == '/':
+		default:
+			return errors.New("compiler_options contains forbidden characters")
+		}
+	}
+	return nil
+}
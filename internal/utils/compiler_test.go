@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCompilerOptions(t *testing.T) {
+	valid := []string{"", "-O2", "-std=c++17 -Wall -DDEBUG=1", "-I/usr/include/foo", "-Wl,--stack,8388608"}
+	for _, opts := range valid {
+		if err := ValidateCompilerOptions(opts); err != nil {
+			t.Errorf("ValidateCompilerOptions(%q) = %v, want nil", opts, err)
+		}
+	}
+
+	invalid := []string{
+		"; rm -rf /",
+		"-O2; rm -rf /",
+		"-O2 && id",
+		"-O2 | tee x",
+		"-DX=`id`",
+		"-DX=$(id)",
+		"-DX=$HOME",
+		"-O2 > /tmp/x",
+		"-DX='a b'",
+		"-O2\nid",
+		"-I*",
+		strings.Repeat("-O2 ", maxCompilerOptionsLength),
+	}
+	for _, opts := range invalid {
+		if err := ValidateCompilerOptions(opts); err == nil {
+			t.Errorf("ValidateCompilerOptions(%q) = nil, want error", opts)
+		}
+	}
+}
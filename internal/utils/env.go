@@ -43,3 +43,16 @@ func EnvBool(key string, fallback bool) bool {
 		return fallback
 	}
 }
+
+// EnvInt64 returns the env value as int64 or fallback on parse error/empty.
+func EnvInt64(key string, fallback int64) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
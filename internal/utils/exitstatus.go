@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+// customExitStatuses maps non-zero exit codes to deployment-defined verdicts.
+var customExitStatuses = map[int]string{}
+
+// ParseExitStatusMap parses a JSON object mapping exit codes to verdict
+// labels, e.g. {"42": "Partial"}.
+func ParseExitStatusMap(raw string) (map[int]string, error) {
+	var entries map[string]string
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, err
+	}
+	statuses := make(map[int]string, len(entries))
+	for key, label := range entries {
+		code, err := strconv.Atoi(key)
+		if err != nil || code < 1 || code > 255 {
+			return nil, fmt.Errorf("invalid exit code %q", key)
+		}
+		if strings.TrimSpace(label) == "" {
+			return nil, fmt.Errorf("empty label for exit code %d", code)
+		}
+		statuses[code] = label
+	}
+	return statuses, nil
+}
+
+// RegisterExitStatuses adds or replaces custom exit code verdicts.
+// It must be called during startup, before jobs are processed.
+func RegisterExitStatuses(statuses map[int]string) {
+	for code, label := range statuses {
+		customExitStatuses[code] = label
+	}
+}
@@ -12,16 +12,36 @@ import (
 
 // Metadata holds parsed isolate execution metadata.
 type Metadata struct {
-	Time     float64
-	Memory   uint64
+	Time    float64
+	HasTime bool
+	// Memory is the larger of CgMem and MaxRSS.
+	Memory uint64
+	// CgMem is the cgroup's peak memory use, from "cg-mem"; MaxRSS is the
+	// largest resident set of a single process, from "max-rss".
+	CgMem    uint64
+	MaxRSS   uint64
 	ExitCode int
-	Message  string
-	Status   string
+	// ExitSignal is the signal that killed the program, from "exitsig".
+	ExitSignal int
+	Message    string
+	Status     string
+	// OOMKilled is set when the cgroup killed the program for running out of
+	// memory, from "cg-oom-killed".
+	OOMKilled bool
 }
 
+// MemoryLimitExceeded reports whether the run was killed for exceeding its
+// memory limit.
+func (m Metadata) MemoryLimitExceeded() bool {
+	return m.OOMKilled || strings.Contains(strings.ToLower(m.Message), "memory limit exceeded")
+}
+
+// JobKeyPrefix starts the Redis key of every job.
+const JobKeyPrefix = "job:"
+
 // JobKey returns the Redis key for a job ID.
 func JobKey(id uint64) string {
-	return "job:" + strconv.FormatUint(id, 10)
+	return JobKeyPrefix + strconv.FormatUint(id, 10)
 }
 
 // ReadFileIfExists reads a file and returns its content as a string.
@@ -43,8 +63,41 @@ func ReadFileIfExists(path string) string {
 	return buf.String()
 }
 
+// TruncationMarker is appended to output cut short by ReadFileCapped.
+const TruncationMarker = "\n...[output truncated]"
+
+// ReadFileCapped reads at most limit bytes of a file. When the file is longer,
+// the returned content ends with TruncationMarker and truncated is true.
+// A limit <= 0 reads the whole file. Missing or unreadable files yield "".
+func ReadFileCapped(path string, limit int64) (content string, truncated bool) {
+	if limit <= 0 {
+		return ReadFileIfExists(path), false
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+
+	// Read one extra byte to learn whether the file exceeds the limit.
+	n, err := io.Copy(buf, io.LimitReader(file, limit+1))
+	if err != nil {
+		return "", false
+	}
+	if n > limit {
+		buf.Truncate(int(limit))
+		buf.WriteString(TruncationMarker)
+		return buf.String(), true
+	}
+	return buf.String(), false
+}
+
 // ReadMetadata parses an isolate metadata file into a Metadata struct.
-func ReadMetadata(path string) (Metadata, error) {
+// Memory values are reported in unit and normalized to kilobytes.
+func ReadMetadata(path string, unit MemoryUnit) (Metadata, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return Metadata{}, err
@@ -56,7 +109,7 @@ func ReadMetadata(path string) (Metadata, error) {
 
 	for scanner.Scan() {
 		line := scanner.Text()
-		
+
 		// Use strings.Cut (Go 1.18+) for efficient splitting
 		key, value, found := strings.Cut(line, ":")
 		if !found {
@@ -65,16 +118,22 @@ func ReadMetadata(path string) (Metadata, error) {
 
 		switch key {
 		case "time":
-			m.Time, _ = strconv.ParseFloat(value, 64)
+			if t, err := strconv.ParseFloat(value, 64); err == nil {
+				m.Time = t
+				m.HasTime = true
+			}
 		case "max-rss":
-			m.Memory, _ = strconv.ParseUint(value, 10, 64)
+			mem, _ := strconv.ParseUint(value, 10, 64)
+			m.MaxRSS = unit.ToKB(mem)
 		case "cg-mem":
 			mem, _ := strconv.ParseUint(value, 10, 64)
-			if mem > m.Memory {
-				m.Memory = mem
-			}
+			m.CgMem = unit.ToKB(mem)
 		case "exitcode":
 			m.ExitCode, _ = strconv.Atoi(value)
+		case "exitsig":
+			m.ExitSignal, _ = strconv.Atoi(value)
+		case "cg-oom-killed":
+			m.OOMKilled = value == "1"
 		case "message":
 			m.Message = value
 		case "status":
@@ -86,45 +145,123 @@ func ReadMetadata(path string) (Metadata, error) {
 		return Metadata{}, err
 	}
 
+	m.Memory = max(m.CgMem, m.MaxRSS)
 	return m, nil
 }
 
-// DetermineStatus maps isolate metadata status to a JobStatus.
-func DetermineStatus(status string, exitCode int, stdout, expected string) models.JobStatus {
-	switch status {
+// DetermineStatus maps isolate metadata status to a JobStatus, comparing
+// stdout to expected with the given comparison mode and float tolerance.
+// When presentationError is set, output that fails the comparison but has the
+// same whitespace-separated tokens is a presentation error rather than a
+// wrong answer.
+func DetermineStatus(meta Metadata, stdout, expected, mode string, tolerance float64, presentationError bool) models.JobStatus {
+	if (meta.Status == "SG" || meta.Status == "RE") && meta.MemoryLimitExceeded() {
+		return models.JobStatus{Kind: models.StatusMemoryLimitExceeded}
+	}
+
+	switch meta.Status {
 	case "TO":
 		return models.JobStatus{Kind: models.StatusTimeLimitExceeded}
 	case "SG":
-		return findRuntimeType(exitCode)
+		sig := meta.ExitSignal
+		if sig == 0 {
+			// Older isolate versions only report the signal via exitcode.
+			sig = meta.ExitCode
+		}
+		return findRuntimeType(sig)
 	case "RE":
+		if label, ok := customExitStatuses[meta.ExitCode]; ok {
+			return models.JobStatus{Kind: models.StatusCustom, Label: label}
+		}
 		return models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "NZEC"}
 	case "XX":
 		return models.JobStatus{Kind: models.StatusInternalError}
 	default:
-		if expected == "" || strings.TrimSpace(stdout) == strings.TrimSpace(expected) {
+		if expected == "" || OutputMatches(stdout, expected, mode, tolerance) {
 			return models.JobStatus{Kind: models.StatusAccepted}
 		}
+		if presentationError && tokensEqual(stdout, expected) {
+			return models.JobStatus{Kind: models.StatusPresentationError}
+		}
 		return models.JobStatus{Kind: models.StatusWrongAnswer}
 	}
 }
 
-// findRuntimeType maps a signal exit code to the appropriate runtime error status.
-func findRuntimeType(exitCode int) models.JobStatus {
-	switch exitCode {
+// diffContextBytes bounds each side of an OutputDiff snippet.
+const diffContextBytes = 64
+
+// DiffOutput returns the first line where stdout and expected differ, after
+// the whitespace trimming mode applies. It returns nil when expected is empty
+// or the outputs match.
+func DiffOutput(stdout, expected, mode string) *models.OutputDiff {
+	if expected == "" {
+		return nil
+	}
+	got := strings.Split(trimForDiff(stdout, mode), "\n")
+	want := strings.Split(trimForDiff(expected, mode), "\n")
+	for i := 0; i < len(got) || i < len(want); i++ {
+		var g, w string
+		if i < len(got) {
+			g = got[i]
+		}
+		if i < len(want) {
+			w = want[i]
+		}
+		if g != w || i >= len(got) || i >= len(want) {
+			return &models.OutputDiff{
+				Line:     i + 1,
+				Expected: diffSnippet(w, g),
+				Actual:   diffSnippet(g, w),
+			}
+		}
+	}
+	return nil
+}
+
+// trimForDiff strips the surrounding whitespace that mode ignores. Modes that
+// ignore whitespace inside the output are trimmed like the default mode.
+func trimForDiff(s, mode string) string {
+	switch mode {
+	case models.ComparisonExact:
+		return s
+	case models.ComparisonTrailingNewline:
+		return trimTrailingNewline(s)
+	default:
+		return strings.TrimSpace(s)
+	}
+}
+
+// diffSnippet returns up to diffContextBytes of line starting shortly before
+// the first byte where it differs from other.
+func diffSnippet(line, other string) string {
+	start := 0
+	for start < len(line) && start < len(other) && line[start] == other[start] {
+		start++
+	}
+	start = max(start-diffContextBytes/4, 0)
+	end := min(start+diffContextBytes, len(line))
+	return line[start:end]
+}
+
+// findRuntimeType maps a signal number to the appropriate runtime error status.
+func findRuntimeType(sig int) models.JobStatus {
+	status := models.JobStatus{Kind: models.StatusRuntimeError, Signal: sig}
+	switch sig {
 	case 11:
-		return models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "SIGSEGV"}
+		status.RuntimeCode = "SIGSEGV"
 	case 25:
-		return models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "SIGXFSZ"}
+		status.RuntimeCode = "SIGXFSZ"
 	case 8:
-		return models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "SIGFPE"}
+		status.RuntimeCode = "SIGFPE"
 	case 6:
-		return models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "SIGABRT"}
+		status.RuntimeCode = "SIGABRT"
 	default:
-		return models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "Other"}
+		status.RuntimeCode = "Other"
 	}
+	return status
 }
 
 func DetectCgroupSupport() bool {
-    _, err := os.Stat("/sys/fs/cgroup")
-    return err == nil
-}
\ No newline at end of file
+	_, err := os.Stat("/sys/fs/cgroup")
+	return err == nil
+}
@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"flash-go/internal/models"
+)
+
+func TestDetermineStatus(t *testing.T) {
+	tests := []struct {
+		name             string
+		meta             Metadata
+		stdout, expected string
+		want             models.JobStatus
+	}{
+		{"accepted", Metadata{}, "3\n", "3", models.JobStatus{Kind: models.StatusAccepted}},
+		{"no expected output", Metadata{}, "anything", "", models.JobStatus{Kind: models.StatusAccepted}},
+		{"wrong answer", Metadata{}, "4", "3", models.JobStatus{Kind: models.StatusWrongAnswer}},
+		{"time limit", Metadata{Status: "TO"}, "", "1", models.JobStatus{Kind: models.StatusTimeLimitExceeded}},
+		{"segfault", Metadata{Status: "SG", ExitSignal: 11}, "", "1", models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "SIGSEGV", Signal: 11}},
+		{"old isolate signal", Metadata{Status: "SG", ExitCode: 8}, "", "1", models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "SIGFPE", Signal: 8}},
+		{"sigkill without oom", Metadata{Status: "SG", ExitSignal: 9}, "", "1", models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "Other", Signal: 9}},
+		{"oom", Metadata{Status: "SG", ExitSignal: 9, OOMKilled: true}, "", "1", models.JobStatus{Kind: models.StatusMemoryLimitExceeded}},
+		{"nzec", Metadata{Status: "RE", ExitCode: 1}, "", "1", models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "NZEC"}},
+		{"internal", Metadata{Status: "XX"}, "", "1", models.JobStatus{Kind: models.StatusInternalError}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetermineStatus(tt.meta, tt.stdout, tt.expected, "", 0, false)
+			if got != tt.want {
+				t.Errorf("DetermineStatus = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetermineStatusPresentationError(t *testing.T) {
+	tests := []struct {
+		name              string
+		stdout, expected  string
+		mode              string
+		presentationError bool
+		want              string
+	}{
+		{"extra spaces default off", "1  2", "1 2", "", false, models.StatusWrongAnswer},
+		{"extra spaces opt in", "1  2", "1 2", "", true, models.StatusPresentationError},
+		{"trailing newline exact off", "1 2\n", "1 2", models.ComparisonExact, false, models.StatusWrongAnswer},
+		{"trailing newline exact opt in", "1 2\n", "1 2", models.ComparisonExact, true, models.StatusPresentationError},
+		{"different tokens opt in", "1 3", "1 2", "", true, models.StatusWrongAnswer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetermineStatus(Metadata{}, tt.stdout, tt.expected, tt.mode, 0, tt.presentationError)
+			if got.Kind != tt.want {
+				t.Errorf("DetermineStatus = %s, want %s", got.Kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetermineStatusCustomExitStatus(t *testing.T) {
+	RegisterExitStatuses(map[int]string{42: "Partial"})
+	defer delete(customExitStatuses, 42)
+
+	want := models.JobStatus{Kind: models.StatusCustom, Label: "Partial"}
+	if got := DetermineStatus(Metadata{Status: "RE", ExitCode: 42}, "", "1", "", 0, false); got != want {
+		t.Errorf("exit 42: DetermineStatus = %+v, want %+v", got, want)
+	}
+	want = models.JobStatus{Kind: models.StatusRuntimeError, RuntimeCode: "NZEC"}
+	if got := DetermineStatus(Metadata{Status: "RE", ExitCode: 43}, "", "1", "", 0, false); got != want {
+		t.Errorf("unmapped exit: DetermineStatus = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffOutput(t *testing.T) {
+	tests := []struct {
+		name             string
+		stdout, expected string
+		mode             string
+		wantLine         int
+	}{
+		{"match", "1\n2\n", "1\n2", "", 0},
+		{"second line", "1\n3\n", "1\n2\n", "", 2},
+		{"missing line", "1", "1\n2", "", 2},
+		{"extra line", "1\n2\n3", "1\n2", "", 3},
+		{"empty expected", "1", "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffOutput(tt.stdout, tt.expected, tt.mode)
+			switch {
+			case tt.wantLine == 0 && diff != nil:
+				t.Errorf("DiffOutput = %+v, want nil", diff)
+			case tt.wantLine != 0 && diff == nil:
+				t.Errorf("DiffOutput = nil, want line %d", tt.wantLine)
+			case diff != nil && diff.Line != tt.wantLine:
+				t.Errorf("DiffOutput line = %d, want %d", diff.Line, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestDiffOutputModes(t *testing.T) {
+	if diff := DiffOutput("1 \n2", "1\n2", models.ComparisonExact); diff == nil || diff.Line != 1 {
+		t.Errorf("exact: trailing space diff = %+v, want line 1", diff)
+	}
+	if diff := DiffOutput("1\n2\n", "1\n2", models.ComparisonExact); diff == nil || diff.Line != 3 {
+		t.Errorf("exact: trailing newline diff = %+v, want line 3", diff)
+	}
+	if diff := DiffOutput("1\n2\n", "1\n2", models.ComparisonTrailingNewline); diff != nil {
+		t.Errorf("trailing_newline: diff = %+v, want nil", diff)
+	}
+}
+
+func TestReadMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("time:0.125\nmax-rss:2048\ncg-mem:4096\nexitcode:3\nexitsig:11\nstatus:SG\ncg-oom-killed:1\nmessage:Caught fatal signal 11\n")
+	meta, err := ReadMetadata(path, MemoryUnitKB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.HasTime || meta.Time != 0.125 {
+		t.Errorf("time = %v (available %v), want 0.125", meta.Time, meta.HasTime)
+	}
+	if meta.Memory != 4096 || meta.CgMem != 4096 || meta.MaxRSS != 2048 {
+		t.Errorf("memory = %d/%d/%d, want 4096/4096/2048", meta.Memory, meta.CgMem, meta.MaxRSS)
+	}
+	if meta.ExitCode != 3 || meta.ExitSignal != 11 || meta.Status != "SG" || !meta.OOMKilled {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+
+	t.Run("missing time", func(t *testing.T) {
+		write("status:XX\nmessage:internal error\n")
+		meta, err := ReadMetadata(path, MemoryUnitKB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.HasTime {
+			t.Errorf("HasTime = true for metadata without time")
+		}
+	})
+}
+
+func TestReadMetadataMemoryUnits(t *testing.T) {
+	dir := t.TempDir()
+	kbPath, bytesPath := filepath.Join(dir, "kb"), filepath.Join(dir, "bytes")
+	if err := os.WriteFile(kbPath, []byte("max-rss:2048\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bytesPath, []byte("max-rss:2097152\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	kb, err := ReadMetadata(kbPath, MemoryUnitKB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bytes, err := ReadMetadata(bytesPath, MemoryUnitBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kb.Memory != 2048 || bytes.Memory != 2048 {
+		t.Errorf("normalized memory = %d (kb) and %d (bytes), want 2048", kb.Memory, bytes.Memory)
+	}
+}
+
+func TestParseMemoryUnit(t *testing.T) {
+	tests := map[string]MemoryUnit{"kb": MemoryUnitKB, " KiB ": MemoryUnitKB, "bytes": MemoryUnitBytes, "b": MemoryUnitBytes}
+	for in, want := range tests {
+		got, err := ParseMemoryUnit(in)
+		if err != nil || got != want {
+			t.Errorf("ParseMemoryUnit(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseMemoryUnit("mb"); err == nil {
+		t.Error("ParseMemoryUnit(\"mb\") = nil error, want error")
+	}
+}
+
+func TestReadFileCapped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, truncated := ReadFileCapped(path, 4)
+	if !truncated || got != "0123"+TruncationMarker {
+		t.Errorf("ReadFileCapped(4) = %q, %v", got, truncated)
+	}
+	got, truncated = ReadFileCapped(path, 10)
+	if truncated || got != "0123456789" {
+		t.Errorf("ReadFileCapped(10) = %q, %v", got, truncated)
+	}
+	got, truncated = ReadFileCapped(path, 0)
+	if truncated || got != "0123456789" {
+		t.Errorf("ReadFileCapped(0) = %q, %v", got, truncated)
+	}
+	if got, _ := ReadFileCapped(filepath.Join(t.TempDir(), "missing"), 4); got != "" {
+		t.Errorf("ReadFileCapped(missing) = %q, want empty", got)
+	}
+}
+
+func TestParseExitStatusMap(t *testing.T) {
+	statuses, err := ParseExitStatusMap(`{"42": "Partial", "7": "Skipped"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[42] != "Partial" || statuses[7] != "Skipped" {
+		t.Errorf("ParseExitStatusMap = %v", statuses)
+	}
+	for _, raw := range []string{`{"0": "Zero"}`, `{"256": "Big"}`, `{"x": "Bad"}`, `{"3": " "}`, `[1]`} {
+		if _, err := ParseExitStatusMap(raw); err == nil {
+			t.Errorf("ParseExitStatusMap(%s) = nil error, want error", raw)
+		}
+	}
+}
+
+func TestJobKey(t *testing.T) {
+	if got := JobKey(42); got != "job:42" {
+		t.Errorf("JobKey(42) = %q", got)
+	}
+	if !strings.HasPrefix(JobKey(1), JobKeyPrefix) {
+		t.Error("JobKey does not start with JobKeyPrefix")
+	}
+}
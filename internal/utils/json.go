@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"io"
+
 	"github.com/goccy/go-json"
 	"flash-go/internal/models"
 
@@ -16,6 +18,13 @@ func UnmarshalJob(data []byte, job *models.Job) error {
 }
 
 
+// BindJSONFast decodes the request body into v. The body is read in full
+// first so read errors, such as an exceeded size limit, reach the caller
+// intact instead of surfacing as a decode error.
 func BindJSONFast(c *gin.Context, v interface{}) error {
-	return json.NewDecoder(c.Request.Body).Decode(v)
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
 }
\ No newline at end of file
@@ -1,21 +1,73 @@
 package utils
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/goccy/go-json"
+)
+
+// judge0LanguageIDs maps Judge0 language IDs to internal language names.
+var judge0LanguageIDs = map[int]string{
+	54:  "cpp",
+	105: "cpp",
+	62:  "java",
+	91:  "java",
+	78:  "kotlin",
+	71:  "python",
+	100: "python",
+	63:  "javascript",
+	102: "javascript",
+	74:  "typescript",
+	72:  "ruby",
+	116: "ruby",
+	51:  "csharp",
+	60:  "go",
+	107: "go",
+	80:  "r",
+}
+
 // Judge0LanguageIDToName maps Judge0 language IDs to internal language names.
 func Judge0LanguageIDToName(id int) (string, bool) {
-	switch id {
-	case 54, 105:
-		return "cpp", true
-	case 62, 91:
-		return "java", true
-	case 71, 100:
-		return "python", true
-	case 63, 102:
-		return "javascript", true
-	case 51:
-		return "csharp", true
-	case 60, 107:
-		return "go", true
-	default:
-		return "", false
+	name, ok := judge0LanguageIDs[id]
+	return name, ok
+}
+
+// Judge0LanguageIDsFor returns the Judge0 IDs mapped to a language name, in ascending order.
+func Judge0LanguageIDsFor(name string) []int {
+	var ids []int
+	for id, langName := range judge0LanguageIDs {
+		if langName == name {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// ParseJudge0LanguageMap parses a JSON object mapping Judge0 IDs to language
+// names, e.g. {"74": "javascript"}.
+func ParseJudge0LanguageMap(raw string) (map[int]string, error) {
+	var entries map[string]string
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		return nil, err
+	}
+	ids := make(map[int]string, len(entries))
+	for key, name := range entries {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Judge0 language id %q", key)
+		}
+		ids[id] = name
+	}
+	return ids, nil
+}
+
+// RegisterJudge0LanguageIDs adds or replaces Judge0 ID mappings.
+// It must be called during startup, before requests are served.
+func RegisterJudge0LanguageIDs(ids map[int]string) {
+	for id, name := range ids {
+		judge0LanguageIDs[id] = name
 	}
 }
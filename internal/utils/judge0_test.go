@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestJudge0LanguageIDsRuby(t *testing.T) {
+	for _, id := range []int{72, 116} {
+		if name, ok := Judge0LanguageIDToName(id); !ok || name != "ruby" {
+			t.Errorf("Judge0LanguageIDToName(%d) = %q, %v; want ruby", id, name, ok)
+		}
+	}
+	if ids := Judge0LanguageIDsFor("ruby"); !slices.Equal(ids, []int{72, 116}) {
+		t.Errorf("Judge0LanguageIDsFor(ruby) = %v, want [72 116]", ids)
+	}
+}
+
+func TestParseJudge0LanguageMap(t *testing.T) {
+	if _, ok := Judge0LanguageIDToName(99999); ok {
+		t.Error("Judge0LanguageIDToName(99999) found a language")
+	}
+	ids, err := ParseJudge0LanguageMap(`{"73": "rust", "74": "javascript"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids[73] != "rust" || ids[74] != "javascript" {
+		t.Errorf("ParseJudge0LanguageMap = %v", ids)
+	}
+	if _, err := ParseJudge0LanguageMap(`{"abc": "rust"}`); err == nil {
+		t.Error("ParseJudge0LanguageMap with a non-numeric id = nil error")
+	}
+}
+
+func TestRegisterJudge0LanguageIDs(t *testing.T) {
+	defer delete(judge0LanguageIDs, 99998)
+	RegisterJudge0LanguageIDs(map[int]string{99998: "python"})
+	if name, ok := Judge0LanguageIDToName(99998); !ok || name != "python" {
+		t.Errorf("registered id maps to %q, %v", name, ok)
+	}
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MemoryUnit is the unit isolate reports max-rss and cg-mem in, expressed
+// as bytes per reported unit. Memory is always normalized to kilobytes.
+type MemoryUnit uint64
+
+const (
+	MemoryUnitKB    MemoryUnit = 1024
+	MemoryUnitBytes MemoryUnit = 1
+)
+
+// ParseMemoryUnit parses "kb" or "bytes".
+func ParseMemoryUnit(s string) (MemoryUnit, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "kb", "k", "kib":
+		return MemoryUnitKB, nil
+	case "bytes", "b":
+		return MemoryUnitBytes, nil
+	default:
+		return 0, fmt.Errorf("unknown memory unit %q (want kb or bytes)", s)
+	}
+}
+
+// ToKB converts a value reported in u to kilobytes.
+func (u MemoryUnit) ToKB(value uint64) uint64 {
+	if u == 0 || u == MemoryUnitKB {
+		return value
+	}
+	return value * uint64(u) / 1024
+}
@@ -0,0 +1,19 @@
+// Package version holds build information injected at link time, e.g.
+//
+//	go build -ldflags "-X flash-go/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X flash-go/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import "runtime"
+
+var (
+	// Commit is the git commit the binary was built from.
+	Commit = "unknown"
+	// BuildTime is when the binary was built, in RFC 3339.
+	BuildTime = "unknown"
+)
+
+// GoVersion returns the Go toolchain the binary was built with.
+func GoVersion() string {
+	return runtime.Version()
+}
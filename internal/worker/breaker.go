@@ -0,0 +1,106 @@
+package worker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultBreakerCooldown = 30 * time.Second
+
+// breakerOpen is set while the circuit breaker is open or half-open.
+var breakerOpen atomic.Bool
+
+// Ready reports whether workers are pulling jobs, i.e. the circuit breaker
+// is closed.
+func Ready() bool {
+	return !breakerOpen.Load()
+}
+
+// breaker stops workers from pulling jobs after too many consecutive internal
+// errors, so a broken host does not fail the whole queue. After the cooldown a
+// single worker is let through to probe; its result closes or reopens the
+// circuit.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether the caller may pull a job. While open it returns
+// false until the cooldown has passed, then true for one probing caller.
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// release gives up a probe slot granted by allow without a result, for
+// example when the queue was empty.
+func (b *breaker) release() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// record counts an execution result. An internal error adds to the
+// consecutive failure count and opens the circuit at the threshold; any other
+// result resets the count and closes it.
+func (b *breaker) record(internalError bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !internalError {
+		if b.open {
+			logrus.Info("circuit breaker closed, resuming job processing")
+		}
+		b.failures = 0
+		b.open = false
+		breakerOpen.Store(false)
+		return
+	}
+
+	b.failures++
+	if b.failures < b.threshold {
+		return
+	}
+	if !b.open {
+		logrus.WithFields(logrus.Fields{
+			"failures": b.failures,
+			"cooldown": b.cooldown,
+		}).Error("circuit breaker opened after consecutive internal errors, pausing job processing")
+	}
+	b.open = true
+	b.openedAt = time.Now()
+	breakerOpen.Store(true)
+}
@@ -0,0 +1,147 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"flash-go/internal/core"
+	"flash-go/internal/models"
+
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	callbackTimeout = 10 * time.Second
+	// callbackDeadline bounds a whole delivery, retries included. It is
+	// independent of the job context so shutdown does not drop callbacks.
+	callbackDeadline        = time.Minute
+	callbackSignatureHeader = "X-Flash-Signature"
+)
+
+// errCallbackAddress is returned when a callback URL resolves to an address
+// workers must not reach.
+var errCallbackAddress = errors.New("callback address is not public")
+
+// newCallbackClient returns the HTTP client used for callbacks. Unless
+// allowPrivate is set it refuses to connect to loopback, private, link-local
+// and other non-public addresses; the check runs on the resolved address so
+// DNS cannot be used to get around it.
+func newCallbackClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: callbackTimeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errCallbackAddress, addrPort.Addr())
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: callbackTimeout, Transport: transport}
+}
+
+// publicAddr reports whether addr is a globally routable unicast address.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !cgnatPrefix.Contains(addr)
+}
+
+// cgnatPrefix is the RFC 6598 shared address space, which IsPrivate misses.
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// sendCallback POSTs the final job result to the job's callback URL.
+// Delivery failures are logged and never propagated to the worker.
+func (w *Worker) sendCallback(job models.Job) {
+	if job.CallbackURL == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), callbackDeadline)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.WithFields(logrus.Fields{
+				"job_id":         job.ID,
+				"correlation_id": job.CorrelationID,
+				"panic":          r,
+			}).Error("callback delivery panic")
+		}
+	}()
+
+	payload, err := json.Marshal(core.NewCheckResponse(&job, w.timestampUnit))
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"job_id": job.ID, "correlation_id": job.CorrelationID}).Error("failed to marshal callback payload")
+		return
+	}
+
+	for attempt := 0; attempt < defaultRetries; attempt++ {
+		err = w.postCallback(ctx, job.CallbackURL, payload)
+		if err == nil {
+			return
+		}
+
+		if errors.Is(err, errCallbackAddress) || attempt+1 >= defaultRetries || ctx.Err() != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"job_id":         job.ID,
+				"correlation_id": job.CorrelationID,
+				"callback_url":   job.CallbackURL,
+				"attempts":       attempt + 1,
+			}).Error("callback delivery failed")
+			return
+		}
+
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"job_id":         job.ID,
+			"correlation_id": job.CorrelationID,
+			"callback_url":   job.CallbackURL,
+			"attempt":        attempt + 1,
+		}).Warn("retrying callback delivery after error")
+
+		time.Sleep(w.retryDelay(attempt))
+	}
+}
+
+func (w *Worker) postCallback(ctx context.Context, callbackURL string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.callbackSecret != "" {
+		req.Header.Set(callbackSignatureHeader, "sha256="+signPayload(w.callbackSecret, payload))
+	}
+
+	resp, err := w.callbackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signPayload returns the hex-encoded HMAC-SHA256 of payload keyed by secret.
+func signPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
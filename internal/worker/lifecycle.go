@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"time"
+
+	"flash-go/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// logJobTransition records a job moving from one status to another together
+// with how long it spent in the previous status, measured from enteredAt
+// (unix nanoseconds). It is a no-op unless transition logging is enabled.
+func (w *Worker) logJobTransition(job *models.Job, idx int, from, to string, enteredAt int64) {
+	if !w.logTransitions {
+		return
+	}
+	fields := logrus.Fields{
+		"event":          "job_transition",
+		"job_id":         job.ID,
+		"correlation_id": job.CorrelationID,
+		"worker_id":      idx,
+		"from":           from,
+		"to":             to,
+	}
+	if enteredAt > 0 {
+		fields["duration_ms"] = time.Since(time.Unix(0, enteredAt)).Milliseconds()
+	}
+	logrus.WithFields(fields).Info("job status transition")
+}
@@ -0,0 +1,190 @@
+package worker
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"flash-go/internal/models"
+)
+
+// statsWindow is how many recent execution times are kept for percentiles.
+const statsWindow = 1024
+
+// Stats is a snapshot of worker throughput since the process started.
+type Stats struct {
+	Processed uint64 `json:"processed"`
+	Active    int64  `json:"active"`
+	// AvgExecutionMs averages every processed job; P95ExecutionMs covers the
+	// most recent statsWindow jobs.
+	AvgExecutionMs float64                  `json:"avg_execution_ms"`
+	P95ExecutionMs float64                  `json:"p95_execution_ms"`
+	Workers        []WorkerStats            `json:"workers"`
+	Languages      map[string]LanguageStats `json:"languages"`
+}
+
+// WorkerStats describes one worker goroutine.
+type WorkerStats struct {
+	ID        int    `json:"id"`
+	Processed uint64 `json:"processed"`
+	Active    bool   `json:"active"`
+}
+
+// LanguageStats describes the jobs processed for one language.
+type LanguageStats struct {
+	Processed      uint64  `json:"processed"`
+	AvgExecutionMs float64 `json:"avg_execution_ms"`
+	P95ExecutionMs float64 `json:"p95_execution_ms"`
+}
+
+type workerCounters struct {
+	processed atomic.Uint64
+	active    atomic.Bool
+}
+
+// durations accumulates execution times: a running total for the average and
+// a ring of recent samples for the percentile.
+type durations struct {
+	count  uint64
+	total  time.Duration
+	recent []time.Duration
+	next   int
+}
+
+func (d *durations) add(elapsed time.Duration) {
+	d.count++
+	d.total += elapsed
+	if len(d.recent) < statsWindow {
+		d.recent = append(d.recent, elapsed)
+		return
+	}
+	d.recent[d.next] = elapsed
+	d.next = (d.next + 1) % statsWindow
+}
+
+func (d *durations) avgMs() float64 {
+	if d.count == 0 {
+		return 0
+	}
+	return float64(d.total.Microseconds()) / float64(d.count) / 1000
+}
+
+func (d *durations) p95Ms() float64 {
+	if len(d.recent) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(d.recent)
+	slices.Sort(sorted)
+	idx := (len(sorted)*95+99)/100 - 1
+	return float64(sorted[idx].Microseconds()) / 1000
+}
+
+type statsCollector struct {
+	processed atomic.Uint64
+	active    atomic.Int64
+
+	workersMu sync.RWMutex
+	workers   []*workerCounters
+
+	mu        sync.Mutex
+	all       durations
+	languages map[string]*durations
+}
+
+// stats is shared with the API through CurrentStats.
+var stats = &statsCollector{languages: make(map[string]*durations)}
+
+// CurrentStats returns a snapshot of worker throughput.
+func CurrentStats() Stats {
+	return stats.snapshot()
+}
+
+// setWorkers sizes the per-worker counters for n workers.
+func (s *statsCollector) setWorkers(n int) {
+	s.workersMu.Lock()
+	defer s.workersMu.Unlock()
+	for len(s.workers) < n {
+		s.workers = append(s.workers, &workerCounters{})
+	}
+}
+
+func (s *statsCollector) worker(idx int) *workerCounters {
+	s.workersMu.RLock()
+	defer s.workersMu.RUnlock()
+	if idx < 0 || idx >= len(s.workers) {
+		return nil
+	}
+	return s.workers[idx]
+}
+
+// begin marks worker idx as running a job.
+func (s *statsCollector) begin(idx int) {
+	s.active.Add(1)
+	if wc := s.worker(idx); wc != nil {
+		wc.active.Store(true)
+	}
+}
+
+// end marks worker idx as idle and counts job if it reached a final status.
+func (s *statsCollector) end(idx int, job *models.Job) {
+	s.active.Add(-1)
+	wc := s.worker(idx)
+	if wc != nil {
+		wc.active.Store(false)
+	}
+	if job.Status.Kind == models.StatusQueued {
+		// Requeued on shutdown; it will be counted where it finishes.
+		return
+	}
+	s.processed.Add(1)
+	if wc != nil {
+		wc.processed.Add(1)
+	}
+
+	var elapsed time.Duration
+	if job.StartedAt > 0 && job.FinishedAt >= job.StartedAt {
+		elapsed = time.Duration(job.FinishedAt - job.StartedAt)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.all.add(elapsed)
+	lang := s.languages[job.Language.Name]
+	if lang == nil {
+		lang = &durations{}
+		s.languages[job.Language.Name] = lang
+	}
+	lang.add(elapsed)
+}
+
+func (s *statsCollector) snapshot() Stats {
+	snap := Stats{
+		Processed: s.processed.Load(),
+		Active:    s.active.Load(),
+		Languages: make(map[string]LanguageStats),
+	}
+
+	s.workersMu.RLock()
+	snap.Workers = make([]WorkerStats, 0, len(s.workers))
+	for i, wc := range s.workers {
+		snap.Workers = append(snap.Workers, WorkerStats{
+			ID:        i,
+			Processed: wc.processed.Load(),
+			Active:    wc.active.Load(),
+		})
+	}
+	s.workersMu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap.AvgExecutionMs = s.all.avgMs()
+	snap.P95ExecutionMs = s.all.p95Ms()
+	for name, d := range s.languages {
+		snap.Languages[name] = LanguageStats{
+			Processed:      d.count,
+			AvgExecutionMs: d.avgMs(),
+			P95ExecutionMs: d.p95Ms(),
+		}
+	}
+	return snap
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"flash-go/internal/models"
+	"flash-go/internal/redis"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pendingTimeoutMessage = "cancelled: timed out in queue"
+	minSweepInterval      = time.Second
+)
+
+// sweepPendingJobs periodically cancels queued jobs that have waited longer
+// than maxPendingTime so polling clients get a terminal status. It returns
+// when ctx is cancelled.
+func (w *Worker) sweepPendingJobs(ctx context.Context) {
+	interval := max(w.maxPendingTime/4, minSweepInterval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, queue := range []redis.Queue{redis.QueuePriority, redis.QueueMain, redis.QueueFree} {
+				w.cancelStaleJobs(ctx, queue)
+			}
+		}
+	}
+}
+
+// cancelStaleJobs removes expired jobs from queue and marks them cancelled.
+// A job is only cancelled if it is still in the queue when removed, so jobs a
+// worker has already picked up are left alone.
+func (w *Worker) cancelStaleJobs(ctx context.Context, queue redis.Queue) {
+	ids, err := w.redis.QueuedJobIDs(ctx, queue)
+	if err != nil || len(ids) == 0 {
+		return
+	}
+	jobs, err := w.redis.GetJobs(ctx, ids)
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-w.maxPendingTime).UnixNano()
+	for _, job := range jobs {
+		if job == nil || job.Status.Kind != models.StatusQueued || job.CreatedAt > cutoff {
+			continue
+		}
+		removed, err := w.redis.RemoveFromQueue(ctx, queue, job.ID)
+		if err != nil || !removed {
+			continue
+		}
+
+		job.Status = models.JobStatus{Kind: models.StatusCancelled}
+		job.Output.Message = pendingTimeoutMessage
+		job.FinishedAt = time.Now().UnixNano()
+		if err := w.redis.StoreJob(ctx, job); err != nil {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"job_id":         job.ID,
+			"correlation_id": job.CorrelationID,
+			"queue":          queue,
+		}).Warn("cancelled job pending too long")
+		w.logJobTransition(job, -1, models.StatusQueued, job.Status.Kind, job.CreatedAt)
+		w.finishJob(ctx, job)
+	}
+}
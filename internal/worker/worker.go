@@ -2,9 +2,16 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
 	"time"
 
+	"flash-go/internal/core"
+	"flash-go/internal/events"
 	"flash-go/internal/isolate"
+	"flash-go/internal/metrics"
 	"flash-go/internal/models"
 	"flash-go/internal/redis"
 
@@ -12,52 +19,210 @@ import (
 )
 
 const (
-	defaultRetries = 3
-	queueTimeout   = time.Second
+	defaultRetries         = 3
+	queueTimeout           = time.Second
+	defaultShutdownTimeout = 30 * time.Second
+	retryBaseDelay         = time.Second
+	retryMaxDelay          = 30 * time.Second
 )
 
+// Config holds worker options.
+type Config struct {
+	// CallbackSecret, when non-empty, signs callback payloads.
+	CallbackSecret string
+	// AllowPrivateCallbacks lets callbacks reach loopback, private and
+	// link-local addresses, for deployments whose receivers live there.
+	AllowPrivateCallbacks bool
+	// ShutdownTimeout bounds how long Start waits for in-flight jobs after
+	// cancellation before killing them and requeueing.
+	ShutdownTimeout time.Duration
+	// Retries is how many times a job is attempted before giving up.
+	// Defaults to defaultRetries.
+	Retries int
+	// RetryJitter is the upper bound of the random delay added to each retry
+	// so jobs failing together do not retry in lockstep.
+	RetryJitter time.Duration
+	// TimestampUnit controls timestamps in callback payloads.
+	TimestampUnit core.TimestampUnit
+	// MaxBoxPool lets the box pool grow on demand up to this many boxes.
+	// Values at or below the base pool size keep the pool fixed.
+	MaxBoxPool int
+	// BoxIdleTimeout is how long a box above the base pool size may sit
+	// unused before it is cleaned up.
+	BoxIdleTimeout time.Duration
+	// LogTransitions logs every job status transition as a structured event.
+	LogTransitions bool
+	// MaxPendingTime, when positive, cancels jobs still queued after this long.
+	MaxPendingTime time.Duration
+	// Events receives an event per finished job. Defaults to events.NopSink.
+	Events events.EventSink
+	// PartialOutputInterval is how often a running job's stdout is flushed to
+	// Redis for streaming clients; 0 disables it.
+	PartialOutputInterval time.Duration
+	// SkipProcessingStore skips persisting the intermediate Processing status,
+	// saving a Redis write per job; clients see Queued until the result lands.
+	SkipProcessingStore bool
+	// BoxPoolSize is the base number of isolate boxes. Defaults to twice the
+	// worker concurrency.
+	BoxPoolSize int
+	// Executor runs jobs. When nil, Start creates one sized by BoxPoolSize.
+	Executor *isolate.Executor
+	// BreakerThreshold is how many consecutive internal errors open the
+	// circuit breaker and pause job processing; 0 disables it.
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open before a probe job
+	// is let through. Defaults to defaultBreakerCooldown.
+	BreakerCooldown time.Duration
+}
+
+// jobRunner executes jobs and frees their boxes. *isolate.Executor is the
+// only implementation outside tests.
+type jobRunner interface {
+	Execute(ctx context.Context, job *models.Job) (models.JobStatus, error)
+	Cleanup(jobID uint64)
+}
+
 type Worker struct {
-	redis    *redis.Client
-	executor *isolate.Executor
+	redis           *redis.Client
+	executor        *isolate.Executor
+	runner          jobRunner
+	callbackSecret  string
+	callbackClient  *http.Client
+	shutdownTimeout time.Duration
+	retries         int
+	retryJitter     time.Duration
+	timestampUnit   core.TimestampUnit
+	maxBoxPool      int
+	boxIdleTimeout  time.Duration
+	logTransitions  bool
+	maxPendingTime  time.Duration
+	events          events.EventSink
+	partialInterval time.Duration
+	skipProcessing  bool
+	boxPoolSize     int
+	breaker         *breaker
+	wg              sync.WaitGroup
+	callbacks       sync.WaitGroup
 }
 
-func New(redisClient *redis.Client) *Worker {
+func New(redisClient *redis.Client, cfg Config) *Worker {
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if cfg.Retries < 1 {
+		cfg.Retries = defaultRetries
+	}
+	if cfg.Events == nil {
+		cfg.Events = events.NopSink{}
+	}
 	return &Worker{
-		redis: redisClient,
+		redis:           redisClient,
+		callbackSecret:  cfg.CallbackSecret,
+		callbackClient:  newCallbackClient(cfg.AllowPrivateCallbacks),
+		shutdownTimeout: cfg.ShutdownTimeout,
+		retries:         cfg.Retries,
+		retryJitter:     cfg.RetryJitter,
+		timestampUnit:   cfg.TimestampUnit,
+		maxBoxPool:      cfg.MaxBoxPool,
+		boxIdleTimeout:  cfg.BoxIdleTimeout,
+		logTransitions:  cfg.LogTransitions,
+		maxPendingTime:  cfg.MaxPendingTime,
+		events:          cfg.Events,
+		partialInterval: cfg.PartialOutputInterval,
+		skipProcessing:  cfg.SkipProcessingStore,
+		boxPoolSize:     cfg.BoxPoolSize,
+		executor:        cfg.Executor,
+		breaker:         newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
 	}
 }
 
 func (w *Worker) Start(ctx context.Context, concurrency int, useBoxPool bool) {
-	poolSize := concurrency * 2
+	poolSize := w.boxPoolSize
 	if poolSize < 1 {
-		poolSize = 1
+		poolSize = max(concurrency*2, 1)
+	}
+	if useBoxPool && poolSize < concurrency {
+		logrus.WithFields(logrus.Fields{
+			"box_pool_size": poolSize,
+			"concurrency":   concurrency,
+		}).Warn("box pool is smaller than worker concurrency, workers will wait for boxes")
 	}
 	if w.executor == nil {
 		w.executor = isolate.NewExecutor(poolSize, useBoxPool)
 	}
+	if w.runner == nil {
+		w.runner = w.executor
+	}
+	w.executor.EnableDynamicPool(ctx, w.maxBoxPool, w.boxIdleTimeout)
+	w.executor.EnablePartialOutput(w.partialInterval, func(job *models.Job, stdout string) {
+		_ = w.redis.StorePartialOutput(context.Background(), job, stdout)
+	})
+
+	// Jobs run on a context that survives shutdown so in-flight work can
+	// finish; it is only cancelled once the drain timeout expires.
+	execCtx, cancelExec := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelExec()
+
+	if w.maxPendingTime > 0 {
+		go w.sweepPendingJobs(ctx)
+	}
 
+	stats.setWorkers(concurrency)
 	for i := 0; i < concurrency; i++ {
-		go w.runLoopWithRecover(ctx, i)
+		w.wg.Add(1)
+		go w.runLoopWithRecover(ctx, execCtx, i)
 	}
 
 	<-ctx.Done()
 	logrus.Info("worker shutdown initiated")
+
+	drained := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		logrus.Info("worker drained in-flight jobs")
+	case <-time.After(w.shutdownTimeout):
+		logrus.WithField("timeout", w.shutdownTimeout).Warn("drain timeout reached, cancelling in-flight jobs")
+		cancelExec()
+		<-drained
+	}
+
+	// Callbacks run on their own deadline; give pending ones the same grace
+	// period before the process exits under them.
+	delivered := make(chan struct{})
+	go func() {
+		w.callbacks.Wait()
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-time.After(w.shutdownTimeout):
+		logrus.WithField("timeout", w.shutdownTimeout).Warn("shutdown with callbacks still pending, they will not be delivered")
+	}
 }
 
-func (w *Worker) runLoopWithRecover(ctx context.Context, idx int) {
+func (w *Worker) runLoopWithRecover(ctx, execCtx context.Context, idx int) {
+	defer w.wg.Done()
 	defer func() {
 		if r := recover(); r != nil {
 			logrus.WithFields(logrus.Fields{
 				"worker_id": idx,
 				"panic":     r,
 			}).Error("worker panic, respawning")
-			go w.runLoopWithRecover(ctx, idx)
+			w.wg.Add(1)
+			go w.runLoopWithRecover(ctx, execCtx, idx)
 		}
 	}()
-	w.runLoop(ctx, idx)
+	w.runLoop(ctx, execCtx, idx)
 }
 
-func (w *Worker) runLoop(ctx context.Context, idx int) {
+// runLoop pulls and processes jobs until ctx is cancelled. Dequeue and
+// execution use execCtx so a job popped during shutdown is never dropped.
+func (w *Worker) runLoop(ctx, execCtx context.Context, idx int) {
 	mainProcessCount := 0
 	for {
 		select {
@@ -67,9 +232,18 @@ func (w *Worker) runLoop(ctx context.Context, idx int) {
 		default:
 		}
 
+		if !w.breaker.allow() {
+			select {
+			case <-ctx.Done():
+			case <-time.After(queueTimeout):
+			}
+			continue
+		}
+
 		preferFree := mainProcessCount%3 == 0
-		job, err := w.nextJob(ctx, preferFree)
+		job, err := w.nextJob(execCtx, preferFree)
 		if err != nil {
+			w.breaker.release()
 			logrus.WithError(err).WithField("worker_id", idx).Error("queue error in worker runLoop")
 			time.Sleep(time.Second / 2)
 			continue
@@ -77,25 +251,24 @@ func (w *Worker) runLoop(ctx context.Context, idx int) {
 		mainProcessCount++
 
 		if job == nil {
+			w.breaker.release()
 			continue
 		}
 
-		w.processJob(ctx, job, idx)
+		w.processJob(execCtx, job, idx)
 	}
 }
 
+// nextJob pops the next job, checking priority before main. The free queue is
+// checked first when preferFree is set so free jobs are never starved, and
+// last otherwise so idle workers still drain it.
 func (w *Worker) nextJob(ctx context.Context, preferFree bool) (*models.Job, error) {
+	queues := []redis.Queue{redis.QueuePriority, redis.QueueMain, redis.QueueFree}
 	if preferFree {
-		job, err := w.redis.GetJobFromFreeQueue(ctx, queueTimeout)
-		if err != nil {
-			return nil, err
-		}
-		if job != nil {
-			return job, nil
-		}
+		queues = []redis.Queue{redis.QueueFree, redis.QueuePriority, redis.QueueMain}
 	}
 
-	job, err := w.redis.GetJobFromMainQueue(ctx, queueTimeout)
+	job, err := w.redis.GetJobFromQueues(ctx, queueTimeout, queues...)
 	if err != nil {
 		return nil, err
 	}
@@ -103,49 +276,163 @@ func (w *Worker) nextJob(ctx context.Context, preferFree bool) (*models.Job, err
 }
 
 func (w *Worker) processJob(ctx context.Context, job *models.Job, idx int) {
-	for attempt := 0; attempt < defaultRetries; attempt++ {
+	// Early exits never record a result, so give back a probe slot the
+	// breaker may have granted for this job.
+	defer w.breaker.release()
+	if !models.CanTransition(job.Status.Kind, models.StatusProcessing) {
+		logrus.WithFields(logrus.Fields{
+			"worker_id":      idx,
+			"job_id":         job.ID,
+			"correlation_id": job.CorrelationID,
+			"status":         job.Status.Kind,
+		}).Warn("skipping job that already finished")
+		return
+	}
+	stats.begin(idx)
+	defer stats.end(idx, job)
+
+	enteredAt := job.CreatedAt
+	for attempt := 0; attempt < w.retries; attempt++ {
+		from := job.Status.Kind
+		job.Attempts++
 		job.Status = models.JobStatus{Kind: models.StatusProcessing}
 		job.StartedAt = time.Now().UnixNano()
+		w.logJobTransition(job, idx, from, job.Status.Kind, enteredAt)
 
-		if err := w.redis.StoreJob(ctx, job); err != nil {
-			logrus.WithError(err).WithFields(logrus.Fields{
-				"worker_id": idx,
-				"job_id":    job.ID,
-				"attempt":   attempt + 1,
-			}).Error("failed to store job status in processJob")
+		if !w.skipProcessing {
+			if err := w.redis.StoreJob(ctx, job); errors.Is(err, redis.ErrInvalidTransition) {
+				// Finished elsewhere, e.g. cancelled, since it was dequeued.
+				return
+			} else if err != nil {
+				logrus.WithError(err).WithFields(logrus.Fields{
+					"worker_id":      idx,
+					"job_id":         job.ID,
+					"correlation_id": job.CorrelationID,
+					"attempt":        attempt + 1,
+				}).Error("failed to store job status in processJob")
+			}
 		}
 
-		_, execErr := w.executor.Execute(ctx, job)
+		_, execErr := w.runner.Execute(ctx, job)
+		job.Output.WorkerID = idx
 
-		if err := w.redis.StoreJob(ctx, job); err != nil {
+		if ctx.Err() != nil {
+			// Execution was cut short by shutdown; the result is not trustworthy.
+			w.runner.Cleanup(job.ID)
+			w.requeueJob(job, idx)
+			return
+		}
+		w.breaker.record(job.Status.Kind == models.StatusInternalError)
+
+		if err := w.redis.StoreJob(ctx, job); errors.Is(err, redis.ErrInvalidTransition) {
+			// Another writer already recorded a final status; keep it.
+			w.runner.Cleanup(job.ID)
+			return
+		} else if err != nil {
 			logrus.WithError(err).WithFields(logrus.Fields{
-				"worker_id": idx,
-				"job_id":    job.ID,
-				"attempt":   attempt + 1,
+				"worker_id":      idx,
+				"job_id":         job.ID,
+				"correlation_id": job.CorrelationID,
+				"attempt":        attempt + 1,
 			}).Error("failed to store job result in processJob")
 		}
 
-		w.executor.Cleanup(job.ID)
+		w.runner.Cleanup(job.ID)
+		w.logJobTransition(job, idx, models.StatusProcessing, job.Status.Kind, job.StartedAt)
+		enteredAt = job.FinishedAt
 
-		if execErr == nil {
+		// Only internal errors are worth retrying; any other verdict would
+		// come out the same on the next attempt.
+		if execErr == nil || job.Status.Kind != models.StatusInternalError {
+			w.finishJob(ctx, job)
 			return
 		}
 
-		if attempt+1 >= defaultRetries {
+		if isolate.IsPermanent(execErr) {
 			logrus.WithError(execErr).WithFields(logrus.Fields{
-				"worker_id": idx,
-				"job_id":    job.ID,
-				"retries":   defaultRetries,
+				"worker_id":      idx,
+				"job_id":         job.ID,
+				"correlation_id": job.CorrelationID,
+				"attempt":        attempt + 1,
+			}).Error("job failed with a permanent error, not retrying")
+			_ = w.redis.PushDeadJob(ctx, job.ID)
+			w.finishJob(ctx, job)
+			return
+		}
+
+		if attempt+1 >= w.retries {
+			logrus.WithError(execErr).WithFields(logrus.Fields{
+				"worker_id":      idx,
+				"job_id":         job.ID,
+				"correlation_id": job.CorrelationID,
+				"retries":        w.retries,
 			}).Error("job failed after all retries")
+			_ = w.redis.PushDeadJob(ctx, job.ID)
+			w.finishJob(ctx, job)
 			return
 		}
 
 		logrus.WithError(execErr).WithFields(logrus.Fields{
-			"worker_id": idx,
-			"job_id":    job.ID,
-			"attempt":   attempt + 1,
+			"worker_id":      idx,
+			"job_id":         job.ID,
+			"correlation_id": job.CorrelationID,
+			"attempt":        attempt + 1,
 		}).Warn("retrying job after error")
 
-		time.Sleep(time.Second) // Brief delay before retry
+		time.Sleep(w.retryDelay(attempt))
+	}
+}
+
+// finishJob records a job that reached its final status: it updates metrics,
+// releases its client's in-flight slot, publishes the finished event and
+// delivers the callback.
+func (w *Worker) finishJob(ctx context.Context, job *models.Job) {
+	metrics.ObserveCompletion(job)
+	if job.InFlightKey != "" {
+		_ = w.redis.ReleaseInFlight(ctx, job.InFlightKey, 1)
+	}
+	if err := w.events.PublishJobFinished(ctx, events.NewJobFinished(job)); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{"job_id": job.ID, "correlation_id": job.CorrelationID}).Warn("failed to publish job finished event")
+	}
+	w.callbacks.Add(1)
+	go func(job models.Job) {
+		defer w.callbacks.Done()
+		w.sendCallback(job)
+	}(*job)
+}
+
+// requeueJob resets an interrupted job and puts it back at the head of its queue.
+func (w *Worker) requeueJob(job *models.Job, idx int) {
+	w.logJobTransition(job, idx, job.Status.Kind, models.StatusQueued, job.StartedAt)
+	job.Status = models.JobStatus{Kind: models.StatusQueued}
+	job.StartedAt = 0
+	job.FinishedAt = 0
+	job.Output = models.JobOutput{}
+
+	if err := w.redis.RequeueJob(context.Background(), job); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"worker_id":      idx,
+			"job_id":         job.ID,
+			"correlation_id": job.CorrelationID,
+		}).Error("failed to requeue interrupted job")
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"worker_id":      idx,
+		"job_id":         job.ID,
+		"correlation_id": job.CorrelationID,
+	}).Warn("requeued interrupted job")
+}
+
+// retryDelay returns the pause after the given zero-based attempt: the base
+// delay doubled per attempt, capped at retryMaxDelay, plus up to retryJitter.
+func (w *Worker) retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << min(attempt, 16)
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	if w.retryJitter <= 0 {
+		return delay
 	}
+	return delay + rand.N(w.retryJitter)
 }
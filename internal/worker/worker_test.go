@@ -0,0 +1,380 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"flash-go/internal/core"
+	"flash-go/internal/models"
+	"flash-go/internal/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestBreaker(t *testing.T) {
+	b := newBreaker(2, 20*time.Millisecond)
+
+	b.record(true)
+	if !b.allow() {
+		t.Fatal("breaker opened below the threshold")
+	}
+	b.record(true)
+	if b.allow() || Ready() {
+		t.Fatal("breaker still closed at the threshold")
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("no probe allowed after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("a second probe was allowed while the first is running")
+	}
+	// A probe that found no job gives its slot back.
+	b.release()
+	if !b.allow() {
+		t.Fatal("probe slot not released")
+	}
+
+	b.record(false)
+	if !b.allow() || !Ready() {
+		t.Fatal("successful probe did not close the breaker")
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := newBreaker(0, 0)
+	for range 10 {
+		b.record(true)
+	}
+	if !b.allow() {
+		t.Error("disabled breaker refused a job")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	w := &Worker{}
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if got := w.retryDelay(attempt); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+	if got := w.retryDelay(40); got != retryMaxDelay {
+		t.Errorf("retryDelay(40) = %v, want %v", got, retryMaxDelay)
+	}
+
+	w.retryJitter = time.Second
+	seen := make(map[time.Duration]bool)
+	for range 20 {
+		d := w.retryDelay(0)
+		if d < time.Second || d >= 2*time.Second {
+			t.Fatalf("retryDelay(0) = %v outside [1s, 2s)", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Error("jitter did not vary the retry delay")
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCallbackRefusesPrivateAddresses(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer srv.Close()
+
+	w := New(nil, Config{})
+	err := w.postCallback(context.Background(), srv.URL, []byte("{}"))
+	if !errors.Is(err, errCallbackAddress) {
+		t.Errorf("postCallback to loopback = %v, want errCallbackAddress", err)
+	}
+	if hits != 0 {
+		t.Errorf("loopback receiver was reached %d times", hits)
+	}
+}
+
+func TestCallbackDelivery(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		body      []byte
+		signature string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(callbackSignatureHeader)
+	}))
+	defer srv.Close()
+
+	w := New(nil, Config{AllowPrivateCallbacks: true, CallbackSecret: "s3cret"})
+	w.sendCallback(models.Job{ID: 7, CallbackURL: srv.URL, Status: models.JobStatus{Kind: models.StatusAccepted}})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(body) == 0 {
+		t.Fatal("callback was not delivered")
+	}
+	if want := "sha256=" + signPayload("s3cret", body); signature != want {
+		t.Errorf("signature = %q, want %q", signature, want)
+	}
+}
+
+func TestSignPayload(t *testing.T) {
+	// HMAC-SHA256("key", "The quick brown fox jumps over the lazy dog").
+	const want = "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got := signPayload("key", []byte("The quick brown fox jumps over the lazy dog")); got != want {
+		t.Errorf("signPayload = %s, want %s", got, want)
+	}
+}
+
+func TestCancelStaleJobs(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rc, err := redis.New("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	stale := models.Job{ID: 1, CreatedAt: time.Now().Add(-time.Hour).UnixNano(), Status: models.JobStatus{Kind: models.StatusQueued}}
+	fresh := models.Job{ID: 2, CreatedAt: time.Now().UnixNano(), Status: models.JobStatus{Kind: models.StatusQueued}}
+	for _, job := range []*models.Job{&stale, &fresh} {
+		if err := rc.Enqueue(ctx, job, redis.QueueMain); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := New(rc, Config{MaxPendingTime: time.Minute})
+	w.cancelStaleJobs(ctx, redis.QueueMain)
+	w.callbacks.Wait()
+
+	ids, err := rc.QueuedJobIDs(ctx, redis.QueueMain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != fresh.ID {
+		t.Errorf("queue = %v, want only the fresh job", ids)
+	}
+	job, err := rc.GetJob(ctx, stale.ID)
+	if err != nil || job == nil {
+		t.Fatalf("stale job: %v", err)
+	}
+	if job.Status.Kind != models.StatusCancelled || job.Output.Message != pendingTimeoutMessage || job.FinishedAt == 0 {
+		t.Errorf("stale job = %+v, want cancelled with the timeout message", job)
+	}
+}
+
+func TestDurationsPercentile(t *testing.T) {
+	var d durations
+	for i := 1; i <= 100; i++ {
+		d.add(time.Duration(i) * time.Millisecond)
+	}
+	if got := d.avgMs(); got != 50.5 {
+		t.Errorf("avgMs = %v, want 50.5", got)
+	}
+	if got := d.p95Ms(); got != 95 {
+		t.Errorf("p95Ms = %v, want 95", got)
+	}
+	var empty durations
+	if empty.avgMs() != 0 || empty.p95Ms() != 0 {
+		t.Error("empty durations report non-zero times")
+	}
+}
+
+// scriptedRunner reports the given verdicts in turn, failing with an
+// internal error for each nil entry.
+type scriptedRunner struct {
+	verdicts []*models.JobStatus
+	calls    int
+}
+
+func (r *scriptedRunner) Execute(ctx context.Context, job *models.Job) (models.JobStatus, error) {
+	verdict := r.verdicts[r.calls]
+	r.calls++
+	job.FinishedAt = time.Now().UnixNano()
+	if verdict == nil {
+		job.Status = models.JobStatus{Kind: models.StatusInternalError}
+		return job.Status, errors.New("isolate crashed")
+	}
+	job.Status = *verdict
+	return job.Status, nil
+}
+
+func (r *scriptedRunner) Cleanup(jobID uint64) {}
+
+// newTestWorker returns a worker backed by miniredis that runs jobs with runner.
+func newTestWorker(t testing.TB, cfg Config, runner jobRunner) (*Worker, *redis.Client) {
+	mr := miniredis.RunT(t)
+	rc, err := redis.New("redis://" + mr.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := New(rc, cfg)
+	w.runner = runner
+	return w, rc
+}
+
+func TestProcessJobCountsAttempts(t *testing.T) {
+	runner := &scriptedRunner{verdicts: []*models.JobStatus{nil, {Kind: models.StatusAccepted}}}
+	w, rc := newTestWorker(t, Config{}, runner)
+	ctx := context.Background()
+	job := models.Job{ID: 1, CreatedAt: time.Now().UnixNano(), Status: models.JobStatus{Kind: models.StatusQueued}}
+	if err := rc.Enqueue(ctx, &job, redis.QueueMain); err != nil {
+		t.Fatal(err)
+	}
+
+	w.processJob(ctx, &job, 0)
+	w.callbacks.Wait()
+
+	stored, err := rc.GetJob(ctx, job.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("stored job: %v", err)
+	}
+	if stored.Attempts != 2 || stored.Status.Kind != models.StatusAccepted {
+		t.Errorf("stored job = %d attempts, %s; want 2 attempts, accepted", stored.Attempts, stored.Status.Kind)
+	}
+	if resp := core.NewCheckResponse(stored, core.TimestampNanoseconds); resp.Attempts != 2 {
+		t.Errorf("response attempts = %d, want 2", resp.Attempts)
+	}
+}
+
+func TestLogJobTransitionSequence(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	runner := &scriptedRunner{verdicts: []*models.JobStatus{{Kind: models.StatusAccepted}}}
+	w, rc := newTestWorker(t, Config{LogTransitions: true}, runner)
+	ctx := context.Background()
+	job := models.Job{ID: 1, CreatedAt: time.Now().UnixNano(), Status: models.JobStatus{Kind: models.StatusQueued}}
+	if err := rc.Enqueue(ctx, &job, redis.QueueMain); err != nil {
+		t.Fatal(err)
+	}
+
+	w.processJob(ctx, &job, 3)
+	w.callbacks.Wait()
+
+	want := [][2]string{
+		{models.StatusQueued, models.StatusProcessing},
+		{models.StatusProcessing, models.StatusAccepted},
+	}
+	var got [][2]string
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["event"] != "job_transition" {
+			continue
+		}
+		got = append(got, [2]string{entry.Data["from"].(string), entry.Data["to"].(string)})
+		if entry.Data["job_id"] != job.ID || entry.Data["worker_id"] != 3 {
+			t.Errorf("transition fields = %v, want job 1 on worker 3", entry.Data)
+		}
+		if ms, ok := entry.Data["duration_ms"].(int64); !ok || ms < 0 {
+			t.Errorf("transition %v has duration_ms %v", got[len(got)-1], entry.Data["duration_ms"])
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+}
+
+// runnerFunc adapts a function to jobRunner.
+type runnerFunc func(ctx context.Context, job *models.Job) (models.JobStatus, error)
+
+func (f runnerFunc) Execute(ctx context.Context, job *models.Job) (models.JobStatus, error) {
+	return f(ctx, job)
+}
+
+func (f runnerFunc) Cleanup(jobID uint64) {}
+
+func TestSkipProcessingStore(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		var rc *redis.Client
+		var seen string
+		w, rc := newTestWorker(t, Config{SkipProcessingStore: skip}, runnerFunc(func(ctx context.Context, job *models.Job) (models.JobStatus, error) {
+			if stored, err := rc.GetJob(ctx, job.ID); err == nil && stored != nil {
+				seen = stored.Status.Kind
+			}
+			job.Status = models.JobStatus{Kind: models.StatusAccepted}
+			return job.Status, nil
+		}))
+		ctx := context.Background()
+		job := models.Job{ID: 1, CreatedAt: time.Now().UnixNano(), Status: models.JobStatus{Kind: models.StatusQueued}}
+		if err := rc.Enqueue(ctx, &job, redis.QueueMain); err != nil {
+			t.Fatal(err)
+		}
+
+		w.processJob(ctx, &job, 0)
+		w.callbacks.Wait()
+
+		want := models.StatusProcessing
+		if skip {
+			want = models.StatusQueued
+		}
+		if seen != want {
+			t.Errorf("skip=%v: stored status while running = %s, want %s", skip, seen, want)
+		}
+		if stored, _ := rc.GetJob(ctx, job.ID); stored == nil || stored.Status.Kind != models.StatusAccepted {
+			t.Errorf("skip=%v: result not stored", skip)
+		}
+	}
+}
+
+func BenchmarkProcessJob(b *testing.B) {
+	accept := runnerFunc(func(ctx context.Context, job *models.Job) (models.JobStatus, error) {
+		job.Status = models.JobStatus{Kind: models.StatusAccepted}
+		return job.Status, nil
+	})
+	for _, skip := range []bool{false, true} {
+		name := "store_processing"
+		if skip {
+			name = "skip_processing"
+		}
+		b.Run(name, func(b *testing.B) {
+			w, rc := newTestWorker(b, Config{SkipProcessingStore: skip}, accept)
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := range b.N {
+				b.StopTimer()
+				job := models.Job{ID: uint64(i + 1), CreatedAt: time.Now().UnixNano(), Status: models.JobStatus{Kind: models.StatusQueued}}
+				if err := rc.StoreJob(ctx, &job); err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+				w.processJob(ctx, &job, 0)
+			}
+			b.StopTimer()
+			w.callbacks.Wait()
+		})
+	}
+}
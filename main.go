@@ -2,43 +2,239 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
+	"strings"
+	"syscall"
+	"time"
 
 	"flash-go/internal/api"
+	"flash-go/internal/core"
+	"flash-go/internal/events"
+	"flash-go/internal/isolate"
 	"flash-go/internal/redis"
+	"flash-go/internal/store"
 	"flash-go/internal/utils"
 	"flash-go/internal/worker"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
+	switch format := utils.EnvString("LOG_FORMAT", "text"); format {
+	case "text":
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		log.Fatalf("invalid LOG_FORMAT: unknown format %q", format)
+	}
 	redisURL := utils.EnvString("REDIS_URL", "redis://127.0.0.1/")
+	redisKeyPrefix := utils.EnvString("REDIS_KEY_PREFIX", "")
 	port := utils.EnvString("PORT", "3001")
 	useBoxPool := utils.EnvBool("USE_BOX_POOL", false)
 	queueLengthLimit := utils.EnvInt("QUEUE_LENGTH_LIMIT", 2000)
+	callbackSecret := utils.EnvString("CALLBACK_SECRET", "")
+	allowPrivateCallbacks := utils.EnvBool("CALLBACK_ALLOW_PRIVATE", false)
+	enqueueBatchWindowMs := utils.EnvInt("ENQUEUE_BATCH_WINDOW_MS", 0)
+	enqueueBatchSize := utils.EnvInt("ENQUEUE_BATCH_SIZE", 128)
+	shutdownTimeout := time.Duration(utils.EnvInt("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second
+	timestampUnit, err := core.ParseTimestampUnit(utils.EnvString("TIMESTAMP_UNIT", "ns"))
+	if err != nil {
+		log.Fatalf("invalid TIMESTAMP_UNIT: %v", err)
+	}
+	if path := utils.EnvString("LANGUAGES_CONFIG", ""); path != "" {
+		if err := core.LoadLanguageConfig(path); err != nil {
+			log.Fatalf("invalid LANGUAGES_CONFIG: %v", err)
+		}
+	}
+	judge0FallbackLanguage := utils.EnvString("JUDGE0_FALLBACK_LANGUAGE", "")
+	if judge0FallbackLanguage != "" {
+		if _, ok := core.LanguageFor(judge0FallbackLanguage, ""); !ok {
+			log.Fatalf("invalid JUDGE0_FALLBACK_LANGUAGE: unknown language %q", judge0FallbackLanguage)
+		}
+	}
+	if raw := utils.EnvString("JUDGE0_LANGUAGE_MAP", ""); raw != "" {
+		ids, err := utils.ParseJudge0LanguageMap(raw)
+		if err != nil {
+			log.Fatalf("invalid JUDGE0_LANGUAGE_MAP: %v", err)
+		}
+		for id, name := range ids {
+			if _, ok := core.LanguageFor(name, ""); !ok {
+				log.Fatalf("invalid JUDGE0_LANGUAGE_MAP: id %d maps to unknown language %q", id, name)
+			}
+		}
+		utils.RegisterJudge0LanguageIDs(ids)
+	}
+	if raw := utils.EnvString("EXIT_CODE_STATUS_MAP", ""); raw != "" {
+		statuses, err := utils.ParseExitStatusMap(raw)
+		if err != nil {
+			log.Fatalf("invalid EXIT_CODE_STATUS_MAP: %v", err)
+		}
+		utils.RegisterExitStatuses(statuses)
+	}
+	retryJitter := time.Duration(utils.EnvInt("RETRY_JITTER_MS", 500)) * time.Millisecond
+	retryCount := utils.EnvInt("RETRY_COUNT", 3)
+	maxBoxPool := utils.EnvInt("BOX_POOL_MAX", 0)
+	boxIdleTimeout := time.Duration(utils.EnvInt("BOX_IDLE_TIMEOUT_SECONDS", 60)) * time.Second
+	logJobTransitions := utils.EnvBool("LOG_JOB_TRANSITIONS", false)
+	maxPendingTime := time.Duration(utils.EnvInt("MAX_PENDING_SECONDS", 0)) * time.Second
+	includeSourceHash := utils.EnvBool("INCLUDE_SOURCE_HASH", false)
+	enableFreeQueue := utils.EnvBool("ENABLE_FREE_QUEUE", true)
+	validateEntrypoint := utils.EnvBool("VALIDATE_ENTRYPOINT", true)
+	adminToken := utils.EnvString("ADMIN_TOKEN", "")
+	skipProcessingStore := utils.EnvBool("SKIP_PROCESSING_STORE", false)
+	maxSourceBytes := utils.EnvInt("MAX_SOURCE_BYTES", 256<<10)
+	maxBatchSize := utils.EnvInt("MAX_BATCH_SIZE", 100)
+	resultTTL := time.Duration(utils.EnvInt("RESULT_TTL_SECONDS", 3600)) * time.Second
+	includeDebug := utils.EnvBool("DEBUG_RESULTS", false)
+	enableCompression := utils.EnvBool("HTTP_COMPRESSION", true)
+	maxBodyBytes := int64(utils.EnvInt("MAX_BODY_BYTES", 32<<20))
+	rateLimitPerMinute := utils.EnvInt("RATE_LIMIT_PER_MINUTE", 0)
+	maxInFlightPerKey := utils.EnvInt("MAX_IN_FLIGHT_PER_KEY", 0)
+	var apiKeys []string
+	for _, key := range strings.Split(utils.EnvString("API_KEYS", ""), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			apiKeys = append(apiKeys, key)
+		}
+	}
+	idempotencyTTL := time.Duration(utils.EnvInt("IDEMPOTENCY_TTL_SECONDS", 600)) * time.Second
+	if idempotencyTTL <= 0 {
+		log.Fatalf("invalid IDEMPOTENCY_TTL_SECONDS: must be positive")
+	}
+	maxResultTTL := time.Duration(utils.EnvInt("MAX_RESULT_TTL_SECONDS", 86400)) * time.Second
+	partialOutputInterval := time.Duration(utils.EnvInt("PARTIAL_OUTPUT_INTERVAL_MS", 1000)) * time.Millisecond
+	var eventSink events.EventSink = events.NopSink{}
+	switch sink := utils.EnvString("EVENT_SINK", ""); sink {
+	case "":
+	case "nats":
+		natsSink, err := events.NewNATSSink(
+			utils.EnvString("NATS_URL", "nats://127.0.0.1:4222"),
+			utils.EnvString("NATS_SUBJECT", "flash.jobs.finished"),
+		)
+		if err != nil {
+			log.Fatalf("nats event sink init failed: %v", err)
+		}
+		eventSink = natsSink
+	default:
+		log.Fatalf("invalid EVENT_SINK: unknown sink %q", sink)
+	}
+	defer eventSink.Close()
 
 	redisClient, err := redis.New(redisURL)
 	if err != nil {
 		log.Fatalf("redis init failed: %v", err)
 	}
 
-	ctx := context.Background()
-	concurrency := runtime.NumCPU() * 2
+	redisClient.SetKeyPrefix(redisKeyPrefix)
+	redisClient.SetDefaultTTL(resultTTL)
 
+	switch kind := utils.EnvString("RESULT_STORE", ""); kind {
+	case "":
+	case "fs":
+		fileStore, err := store.NewFileStore(utils.EnvString("RESULT_STORE_DIR", "/var/lib/flash/results"))
+		if err != nil {
+			log.Fatalf("result store init failed: %v", err)
+		}
+		redisClient.SetResultStore(fileStore)
+	case "postgres":
+		pgStore, err := store.NewPostgresStore(context.Background(), utils.EnvString("RESULT_STORE_DSN", ""))
+		if err != nil {
+			log.Fatalf("result store init failed: %v", err)
+		}
+		defer pgStore.Close()
+		redisClient.SetResultStore(pgStore)
+	default:
+		log.Fatalf("invalid RESULT_STORE: unknown store %q", kind)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	redisClient.EnableEnqueueBatching(ctx, time.Duration(enqueueBatchWindowMs)*time.Millisecond, enqueueBatchSize)
+	concurrency := utils.EnvInt("WORKER_CONCURRENCY", runtime.NumCPU()*2)
+	if concurrency < 1 {
+		log.Fatalf("invalid WORKER_CONCURRENCY: must be at least 1")
+	}
+	boxPoolSize := utils.EnvInt("BOX_POOL_SIZE", concurrency*2)
+	if boxPoolSize < 1 {
+		log.Fatalf("invalid BOX_POOL_SIZE: must be at least 1")
+	}
+	breakerThreshold := utils.EnvInt("CIRCUIT_BREAKER_THRESHOLD", 10)
+	breakerCooldown := time.Duration(utils.EnvInt("CIRCUIT_BREAKER_COOLDOWN_SECONDS", 30)) * time.Second
+	isolate.SelfTest(ctx)
+	executor := isolate.NewExecutor(boxPoolSize, useBoxPool)
+
+	workerDone := make(chan struct{})
 	go func() {
-		worker.New(redisClient).Start(ctx, concurrency, useBoxPool)
+		defer close(workerDone)
+		worker.New(redisClient, worker.Config{
+			CallbackSecret:        callbackSecret,
+			AllowPrivateCallbacks: allowPrivateCallbacks,
+			ShutdownTimeout:       shutdownTimeout,
+			Retries:               retryCount,
+			RetryJitter:           retryJitter,
+			TimestampUnit:         timestampUnit,
+			MaxBoxPool:            maxBoxPool,
+			BoxIdleTimeout:        boxIdleTimeout,
+			LogTransitions:        logJobTransitions,
+			MaxPendingTime:        maxPendingTime,
+			Events:                eventSink,
+			PartialOutputInterval: partialOutputInterval,
+			SkipProcessingStore:   skipProcessingStore,
+			BoxPoolSize:           boxPoolSize,
+			Executor:              executor,
+			BreakerThreshold:      breakerThreshold,
+			BreakerCooldown:       breakerCooldown,
+		}).Start(ctx, concurrency, useBoxPool)
 	}()
 
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(gin.Recovery())
-	api.RegisterRoutes(router, api.NewHandler(redisClient, queueLengthLimit, concurrency, useBoxPool))
+	api.RegisterRoutes(router, api.NewHandler(redisClient, api.Config{
+		QueueLengthLimit:       queueLengthLimit,
+		WorkerConcurrency:      concurrency,
+		UseBoxPool:             useBoxPool,
+		TimestampUnit:          timestampUnit,
+		Judge0FallbackLanguage: judge0FallbackLanguage,
+		IncludeSourceHash:      includeSourceHash,
+		DisableFreeQueue:       !enableFreeQueue,
+		ValidateEntrypoint:     validateEntrypoint,
+		AdminToken:             adminToken,
+		MaxSourceBytes:         maxSourceBytes,
+		MaxBatchSize:           maxBatchSize,
+		MaxResultTTL:           maxResultTTL,
+		IncludeDebug:           includeDebug,
+		DisableCompression:     !enableCompression,
+		MaxBodyBytes:           maxBodyBytes,
+		RateLimitPerMinute:     rateLimitPerMinute,
+		MaxInFlightPerKey:      maxInFlightPerKey,
+		IdempotencyTTL:         idempotencyTTL,
+		APIKeys:                apiKeys,
+		Executor:               executor,
+	}))
 
 	addr := ":" + port
-	log.Printf("Server running on http://0.0.0.0%s", addr)
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("server failed: %v", err)
+	server := &http.Server{Addr: addr, Handler: router}
+	go func() {
+		log.Printf("Server running on http://0.0.0.0%s", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("shutdown signal received, draining")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown failed: %v", err)
 	}
+	<-workerDone
+	log.Printf("shutdown complete")
 }